@@ -0,0 +1,124 @@
+package mydb
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+)
+
+const (
+	// affinityVirtualNodes is the number of points each readreplica owns on the hash ring.
+	affinityVirtualNodes = 128
+
+	DefaultAffinityLoadFactor = 1.25
+)
+
+type affinityKeyType struct{}
+
+var affinityKey = affinityKeyType{}
+
+// WithAffinityKey returns a copy of ctx carrying an affinity key such as a tenant ID,
+// a user ID or a table name. Reads with the same key are routed to the same readreplica
+// as long as it is available and not overloaded.
+func WithAffinityKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, affinityKey, key)
+}
+
+// AffinityKeyFromContext returns the affinity key carried by ctx, if any.
+func AffinityKeyFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	key, ok := ctx.Value(affinityKey).(string)
+	return key, ok
+}
+
+var _ interface {
+	Get(key string, available []*node, loadFactor float64) *node
+} = newHashRing(nil)
+
+// hashRing is a consistent hash ring with bounded loads.
+// The ring is built from all readreplicas, so a readreplica leaving or joining
+// the available list only moves the keys it owns.
+type hashRing struct {
+	points []uint32
+	owners []*node
+}
+
+func newHashRing(nodes []*node) *hashRing {
+	r := &hashRing{
+		points: make([]uint32, 0, len(nodes)*affinityVirtualNodes),
+		owners: make([]*node, 0, len(nodes)*affinityVirtualNodes),
+	}
+
+	type point struct {
+		hash  uint32
+		owner *node
+	}
+	points := make([]point, 0, len(nodes)*affinityVirtualNodes)
+	for i := range nodes {
+		for v := 0; v < affinityVirtualNodes; v++ {
+			points = append(points, point{
+				hash:  hashKey(nodes[i].Name() + "#" + strconv.Itoa(v)),
+				owner: nodes[i],
+			})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].hash < points[j].hash
+	})
+
+	for i := range points {
+		r.points = append(r.points, points[i].hash)
+		r.owners = append(r.owners, points[i].owner)
+	}
+
+	return r
+}
+
+func hashKey(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
+
+// Get returns the node owning key among available nodes.
+// A node is skipped while its outstanding requests exceed
+// loadFactor times the average load of available nodes.
+func (r *hashRing) Get(key string, available []*node, loadFactor float64) *node {
+	if len(r.points) == 0 || len(available) == 0 {
+		return nil
+	}
+
+	var total int64
+	loads := make(map[*node]int64, len(available))
+	for i := range available {
+		loads[available[i]] = available[i].load()
+		total += loads[available[i]]
+	}
+	limit := int64(math.Ceil(loadFactor * float64(total+1) / float64(len(available))))
+
+	hash := hashKey(key)
+	start := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i] >= hash
+	})
+
+	var first *node
+	for i := 0; i < len(r.points); i++ {
+		n := r.owners[(start+i)%len(r.points)]
+		load, ok := loads[n]
+		if !ok {
+			continue
+		}
+		if first == nil {
+			first = n
+		}
+		if load+1 <= limit {
+			return n
+		}
+	}
+
+	return first
+}
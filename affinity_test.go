@@ -0,0 +1,109 @@
+package mydb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestWithAffinityKey(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ctx := WithAffinityKey(context.Background(), "tenant-1")
+		key, ok := AffinityKeyFromContext(ctx)
+		if !ok || key != "tenant-1" {
+			t.Errorf("AffinityKeyFromContext() want %s, but get %s", "tenant-1", key)
+		}
+	})
+
+	t.Run("success without key", func(t *testing.T) {
+		if _, ok := AffinityKeyFromContext(context.Background()); ok {
+			t.Error("AffinityKeyFromContext() want no key")
+		}
+	})
+}
+
+func TestHashRingGet(t *testing.T) {
+	newNodes := func(n int) []*node {
+		nodes := make([]*node, n)
+		for i := range nodes {
+			nodes[i] = newNode(ReadReplica, fmt.Sprintf("readreplica%d", i), nil)
+		}
+		return nodes
+	}
+
+	t.Run("success stable", func(t *testing.T) {
+		nodes := newNodes(3)
+		ring := newHashRing(nodes)
+
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("key%d", i)
+			if ring.Get(key, nodes, DefaultAffinityLoadFactor) != ring.Get(key, nodes, DefaultAffinityLoadFactor) {
+				t.Errorf("Get(%s) want same node", key)
+			}
+		}
+	})
+
+	t.Run("success with node leaving", func(t *testing.T) {
+		nodes := newNodes(3)
+		ring := newHashRing(nodes)
+
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("key%d", i)
+			before := ring.Get(key, nodes, DefaultAffinityLoadFactor)
+			after := ring.Get(key, nodes[:2], DefaultAffinityLoadFactor)
+			if before != nodes[2] && before != after {
+				t.Errorf("Get(%s) want not moved", key)
+			}
+		}
+	})
+
+	t.Run("success with bounded load", func(t *testing.T) {
+		nodes := newNodes(2)
+		ring := newHashRing(nodes)
+
+		owner := ring.Get("key", nodes, DefaultAffinityLoadFactor)
+		for i := 0; i < 10; i++ {
+			owner.begin()
+		}
+
+		if ring.Get("key", nodes, DefaultAffinityLoadFactor) == owner {
+			t.Error("Get() want other node when owner is overloaded")
+		}
+	})
+
+	t.Run("success empty", func(t *testing.T) {
+		ring := newHashRing(nil)
+		if ring.Get("key", nil, DefaultAffinityLoadFactor) != nil {
+			t.Error("Get() want nil")
+		}
+	})
+}
+
+func TestGetContextWithAffinityKey(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db0, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		db1, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		dbBalancer := NewDbBalancer(context.Background(), []*sql.DB{db0, db1})
+		defer dbBalancer.Destroy()
+
+		ctx := WithAffinityKey(context.Background(), "tenant-1")
+		want, err := dbBalancer.GetContext(ctx)
+		if err != nil || want == nil {
+			t.Fatal("GetContext() want db")
+		}
+		for i := 0; i < 10; i++ {
+			if got, _ := dbBalancer.GetContext(ctx); got != want {
+				t.Error("GetContext() want same db")
+			}
+		}
+	})
+}
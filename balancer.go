@@ -0,0 +1,56 @@
+package mydb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Node is a readreplica as seen by a Balancer.
+type Node interface {
+	Name() string
+	Zone() string
+	DB() *sql.DB
+	// Inflight returns the number of outstanding requests routed by mydb.
+	// A request ends when the query returns, so rows still being read are not counted.
+	// DB().Stats().InUse includes them.
+	Inflight() int64
+	// Latency returns the moving average latency of requests routed by mydb.
+	Latency() time.Duration
+}
+
+// Balancer chooses the readreplica for each read.
+// It replaces the BalanceAlgorithm when registered by SetBalancer.
+//
+// Pick is called with the healthy readreplicas, already narrowed down to
+// the local zone if configured, and must be safe for concurrent use.
+// ObserveLatency and ObserveError are called after each read on the picked node.
+type Balancer interface {
+	Pick(ctx context.Context, candidates []Node) (Node, error)
+	ObserveLatency(n Node, d time.Duration)
+	ObserveError(n Node, err error)
+}
+
+func (d *dbBalancer) pick(ctx context.Context, b Balancer, list *dbList) (*sql.DB, error) {
+	dbs := list.List()
+	candidates := make([]Node, len(dbs))
+	for i := range dbs {
+		candidates[i] = d.nodes[dbs[i]]
+	}
+
+	n, err := b.Pick(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, ErrAllReadreplicaDied
+	}
+
+	// the picked node must be one of the candidates
+	for i := range dbs {
+		if n.DB() != nil && n.DB() == dbs[i] {
+			return dbs[i], nil
+		}
+	}
+	return nil, ErrUnknownNode
+}
@@ -0,0 +1,154 @@
+package mydb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type lastNodeBalancer struct {
+	lk        sync.Mutex
+	err       error
+	node      Node
+	latencies int
+	errors    int
+}
+
+func (b *lastNodeBalancer) Pick(ctx context.Context, candidates []Node) (Node, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if b.node != nil {
+		return b.node, nil
+	}
+	return candidates[len(candidates)-1], nil
+}
+
+func (b *lastNodeBalancer) ObserveLatency(n Node, d time.Duration) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	b.latencies++
+}
+
+func (b *lastNodeBalancer) ObserveError(n Node, err error) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	b.errors++
+}
+
+func TestSetBalancer(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica0, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1, readreplica1Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1Mock.ExpectQuery("select 1").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+		readreplica1Mock.ExpectQuery("select 2").
+			WillReturnError(errors.New("query error"))
+
+		db := New(master, readreplica0, readreplica1)
+		defer db.Close()
+
+		b := &lastNodeBalancer{}
+		db.SetBalancer(b)
+		if db.GetBalancer() != b {
+			t.Error("GetBalancer() want registered balancer")
+		}
+
+		_, err = db.Query("select 1")
+		if err != nil {
+			t.Error(err)
+		}
+		_, err = db.Query("select 2")
+		if err == nil {
+			t.Error("Query() want error")
+		}
+
+		if b.latencies != 2 {
+			t.Errorf("ObserveLatency() want called %d times, but %d", 2, b.latencies)
+		}
+		if b.errors != 1 {
+			t.Errorf("ObserveError() want called %d times, but %d", 1, b.errors)
+		}
+		if err := readreplica1Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("error with pick", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		db := New(master, readreplica)
+		defer db.Close()
+
+		pickErr := errors.New("pick error")
+		db.SetBalancer(&lastNodeBalancer{err: pickErr})
+
+		_, err = db.Query("select 1")
+		if err != pickErr {
+			t.Errorf("Query() want %s, but get %v", pickErr, err)
+		}
+	})
+
+	t.Run("error with unknown node", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		other, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		db := New(master, readreplica)
+		defer db.Close()
+
+		for _, n := range []Node{newNode(ReadReplica, "other", other), newNode(ReadReplica, "nil", nil)} {
+			db.SetBalancer(&lastNodeBalancer{node: n})
+			if _, err := db.Query("select 1"); err != ErrUnknownNode {
+				t.Errorf("Query() want %s, but get %v", ErrUnknownNode, err)
+			}
+		}
+	})
+
+	t.Run("error with unknown balance algorithm", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		db := New(master, readreplica)
+		defer db.Close()
+		db.SetBalanceAlgorithm(BalanceAlgorithm(-1))
+
+		_, err = db.Query("select 1")
+		if err != ErrUnknownBalanceAlgorithm {
+			t.Errorf("Query() want %s, but get %v", ErrUnknownBalanceAlgorithm, err)
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package mydb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBreakerOpenTimeout      = 10 * time.Second
+	DefaultBreakerHalfOpenMaxCalls = 1
+)
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker configures the circuit breaker of each node.
+// A breaker opens after FailureThreshold consecutive failures, where a failure is
+// a connection error, a timeout or a call slower than SlowCallThreshold.
+// After OpenTimeout it becomes half-open and lets HalfOpenMaxCalls trial calls through.
+// It closes when all of them succeed, and opens again on any failure.
+// A trial which does not finish within OpenTimeout is given up, so that another call can try.
+// The zero value disables circuit breakers.
+type CircuitBreaker struct {
+	FailureThreshold int
+	// SlowCallThreshold counts calls slower than this as failures. 0 disables it.
+	SlowCallThreshold time.Duration
+	// OpenTimeout is DefaultBreakerOpenTimeout if 0.
+	OpenTimeout time.Duration
+	// HalfOpenMaxCalls is DefaultBreakerHalfOpenMaxCalls if 0.
+	HalfOpenMaxCalls int
+}
+
+func (c CircuitBreaker) enabled() bool {
+	return c.FailureThreshold > 0
+}
+
+func (c CircuitBreaker) openTimeout() time.Duration {
+	if c.OpenTimeout <= 0 {
+		return DefaultBreakerOpenTimeout
+	}
+	return c.OpenTimeout
+}
+
+func (c CircuitBreaker) halfOpenMaxCalls() int {
+	if c.HalfOpenMaxCalls <= 0 {
+		return DefaultBreakerHalfOpenMaxCalls
+	}
+	return c.HalfOpenMaxCalls
+}
+
+func (c CircuitBreaker) isFailure(err error, latency time.Duration) bool {
+	if isConnError(err) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	return c.SlowCallThreshold > 0 && latency > c.SlowCallThreshold
+}
+
+type breaker struct {
+	lk        sync.Mutex
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	trials    int
+	trialAt   time.Time
+	successes int
+}
+
+// stateAt moves an open breaker to half-open once the open timeout has passed.
+// b.lk must be held.
+func (b *breaker) stateAt(c CircuitBreaker, now time.Time) BreakerState {
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= c.openTimeout() {
+		b.state = BreakerHalfOpen
+		b.trials = 0
+		b.successes = 0
+	}
+	return b.state
+}
+
+// State returns the current state of the breaker.
+func (b *breaker) State(c CircuitBreaker, now time.Time) BreakerState {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	if !c.enabled() {
+		return BreakerClosed
+	}
+	return b.stateAt(c, now)
+}
+
+// allow reports whether a call may go through. In half-open state it
+// counts the call as a trial.
+func (b *breaker) allow(c CircuitBreaker, now time.Time) bool {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	if !c.enabled() {
+		return true
+	}
+
+	switch b.stateAt(c, now) {
+	case BreakerClosed:
+		return true
+	case BreakerHalfOpen:
+		if b.hasTrialAt(c, now) {
+			b.trials++
+			b.trialAt = now
+			return true
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// admits reports whether allow would let a call through, without counting a trial.
+func (b *breaker) admits(c CircuitBreaker, now time.Time) bool {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	if !c.enabled() {
+		return true
+	}
+
+	switch b.stateAt(c, now) {
+	case BreakerClosed:
+		return true
+	case BreakerHalfOpen:
+		return b.hasTrialAt(c, now)
+	default:
+		return false
+	}
+}
+
+// hasTrialAt reports whether a half-open breaker has a trial left.
+// Trials whose result has not been observed within the open timeout are given up.
+// b.lk must be held.
+func (b *breaker) hasTrialAt(c CircuitBreaker, now time.Time) bool {
+	if b.trials > b.successes && now.Sub(b.trialAt) >= c.openTimeout() {
+		b.trials = b.successes
+	}
+	return b.trials < c.halfOpenMaxCalls()
+}
+
+// observe records the result of a call and reports whether the breaker has just opened.
+func (b *breaker) observe(c CircuitBreaker, err error, latency time.Duration, now time.Time) bool {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	if !c.enabled() {
+		return false
+	}
+
+	failed := c.isFailure(err, latency)
+	switch b.stateAt(c, now) {
+	case BreakerClosed:
+		if !failed {
+			b.failures = 0
+			return false
+		}
+		b.failures++
+		if b.failures < c.FailureThreshold {
+			return false
+		}
+	case BreakerHalfOpen:
+		if !failed {
+			b.successes++
+			if b.successes >= c.halfOpenMaxCalls() {
+				b.state = BreakerClosed
+				b.failures = 0
+			}
+			return false
+		}
+	default:
+		return false
+	}
+
+	b.state = BreakerOpen
+	b.openedAt = now
+	return true
+}
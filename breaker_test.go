@@ -0,0 +1,289 @@
+package mydb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBreaker(t *testing.T) {
+	connErr := errors.New("invalid connection")
+	c := CircuitBreaker{
+		FailureThreshold:  2,
+		SlowCallThreshold: time.Second,
+		OpenTimeout:       time.Minute,
+		HalfOpenMaxCalls:  2,
+	}
+	now := time.Now()
+
+	t.Run("success with disabled", func(t *testing.T) {
+		b := &breaker{}
+		for i := 0; i < 10; i++ {
+			b.observe(CircuitBreaker{}, connErr, 0, now)
+		}
+		if !b.allow(CircuitBreaker{}, now) {
+			t.Error("allow() want true")
+		}
+	})
+
+	t.Run("success with open", func(t *testing.T) {
+		b := &breaker{}
+		if b.observe(c, connErr, 0, now) {
+			t.Error("observe() want false after 1 failure")
+		}
+		if !b.observe(c, nil, 2*time.Second, now) {
+			t.Error("observe() want true after 1 failure and 1 slow call")
+		}
+		if b.State(c, now) != BreakerOpen {
+			t.Errorf("State() want %s, but get %s", BreakerOpen, b.State(c, now))
+		}
+		if b.allow(c, now) {
+			t.Error("allow() want false")
+		}
+	})
+
+	t.Run("success with statement error", func(t *testing.T) {
+		b := &breaker{}
+		b.observe(c, errors.New("Error 1062: Duplicate entry"), 0, now)
+		b.observe(c, errors.New("Error 1062: Duplicate entry"), 0, now)
+		if b.State(c, now) != BreakerClosed {
+			t.Errorf("State() want %s, but get %s", BreakerClosed, b.State(c, now))
+		}
+	})
+
+	t.Run("success with half-open and close", func(t *testing.T) {
+		b := &breaker{}
+		b.observe(c, connErr, 0, now)
+		b.observe(c, connErr, 0, now)
+
+		later := now.Add(time.Minute)
+		if b.State(c, later) != BreakerHalfOpen {
+			t.Errorf("State() want %s, but get %s", BreakerHalfOpen, b.State(c, later))
+		}
+		if !b.admits(c, later) || !b.admits(c, later) || !b.admits(c, later) {
+			t.Error("admits() want true without taking trial calls")
+		}
+		if !b.allow(c, later) || !b.allow(c, later) {
+			t.Error("allow() want true for trial calls")
+		}
+		if b.admits(c, later) {
+			t.Error("admits() want false over max trial calls")
+		}
+		if b.allow(c, later) {
+			t.Error("allow() want false over max trial calls")
+		}
+
+		b.observe(c, nil, 0, later)
+		b.observe(c, nil, 0, later)
+		if b.State(c, later) != BreakerClosed {
+			t.Errorf("State() want %s, but get %s", BreakerClosed, b.State(c, later))
+		}
+	})
+
+	t.Run("success with half-open and lost trial", func(t *testing.T) {
+		b := &breaker{}
+		b.observe(c, connErr, 0, now)
+		b.observe(c, connErr, 0, now)
+
+		later := now.Add(time.Minute)
+		b.allow(c, later)
+		b.allow(c, later)
+		if b.allow(c, later) {
+			t.Error("allow() want false while trials are running")
+		}
+		if !b.allow(c, later.Add(time.Minute)) {
+			t.Error("allow() want true after trials timed out")
+		}
+	})
+
+	t.Run("success with half-open and reopen", func(t *testing.T) {
+		b := &breaker{}
+		b.observe(c, connErr, 0, now)
+		b.observe(c, connErr, 0, now)
+
+		later := now.Add(time.Minute)
+		b.allow(c, later)
+		if !b.observe(c, connErr, 0, later) {
+			t.Error("observe() want true on trial failure")
+		}
+		if b.State(c, later) != BreakerOpen {
+			t.Errorf("State() want %s, but get %s", BreakerOpen, b.State(c, later))
+		}
+	})
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Run("success with master", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		masterMock.ExpectExec("insert").
+			WillReturnError(errors.New("invalid connection"))
+
+		db := New(master)
+		defer db.Close()
+		db.SetCircuitBreaker(CircuitBreaker{FailureThreshold: 1})
+		if db.GetCircuitBreaker().FailureThreshold != 1 {
+			t.Error("GetCircuitBreaker() want FailureThreshold 1")
+		}
+
+		if _, err = db.Exec("insert"); err == nil {
+			t.Error("Exec() want error")
+		}
+		if _, err = db.Exec("insert"); err != ErrCircuitOpen {
+			t.Errorf("Exec() want %s, but get %v", ErrCircuitOpen, err)
+		}
+		if db.HealthStates()[0].Breaker != BreakerOpen {
+			t.Error("HealthStates() want open breaker")
+		}
+
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("success with readreplica", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica0, readreplica0Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1, readreplica1Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1Mock.ExpectQuery("select 1").
+			WillReturnError(errors.New("invalid connection"))
+		for i := 0; i < 3; i++ {
+			readreplica0Mock.ExpectQuery("select 1").
+				WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+		}
+
+		db := New(master, readreplica0, readreplica1)
+		defer db.Close()
+		db.SetBalanceAlgorithm(RoundRobin)
+		db.SetRetryBudgetRatio(0)
+		db.SetCircuitBreaker(CircuitBreaker{FailureThreshold: 1})
+
+		if _, err = db.Query("select 1"); err == nil {
+			t.Error("Query() want error")
+		}
+		for i := 0; i < 3; i++ {
+			if _, err = db.Query("select 1"); err != nil {
+				t.Error(err)
+			}
+		}
+		if db.HealthStates()[2].Breaker != BreakerOpen {
+			t.Error("HealthStates() want open breaker of readreplica1")
+		}
+
+		if err := readreplica0Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+		if err := readreplica1Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("success with fallback while trial is running", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		masterMock.ExpectQuery("select 1").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+
+		db := New(master, readreplica)
+		defer db.Close()
+		db.SetCircuitBreaker(CircuitBreaker{FailureThreshold: 1})
+
+		takeTrial(&db.readDbBalancer.nodes[readreplica].breaker)
+
+		if _, err = db.Query("select 1"); err != nil {
+			t.Errorf("Query() want fallback to master, but get %v", err)
+		}
+
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("success with remote zone while local trial is running", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		local, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		remote, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		db := New(master, local, remote)
+		defer db.Close()
+		db.SetFallbackType(None)
+		db.SetCircuitBreaker(CircuitBreaker{FailureThreshold: 1})
+		db.SetReplicaZone(local, "zone-a")
+		db.SetReplicaZone(remote, "zone-b")
+		db.SetLocalZone("zone-a")
+		takeTrial(&db.readDbBalancer.nodes[local].breaker)
+
+		d, err := db.getReadReplica(context.Background())
+		if err != nil || d != remote {
+			t.Errorf("getReadReplica() want remote readreplica, but get %v", err)
+		}
+	})
+
+	t.Run("success with balancer while trial is running", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica0, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		db := New(master, readreplica0, readreplica1)
+		defer db.Close()
+		db.SetCircuitBreaker(CircuitBreaker{FailureThreshold: 1})
+		// picks readreplica1, whose only trial is running
+		db.SetBalancer(&lastNodeBalancer{})
+		takeTrial(&db.readDbBalancer.nodes[readreplica1].breaker)
+
+		for i := 0; i < 3; i++ {
+			d, err := db.getReadReplica(context.Background())
+			if err != nil || d != readreplica0 {
+				t.Errorf("getReadReplica() want readreplica0, but get %v", err)
+			}
+		}
+	})
+}
+
+// takeTrial makes b half-open with its only trial running.
+func takeTrial(b *breaker) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	b.state = BreakerHalfOpen
+	b.trials = 1
+	b.trialAt = time.Now()
+}
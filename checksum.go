@@ -0,0 +1,313 @@
+package mydb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChecksumDiff is a table or a chunk of a table whose checksum on a readreplica
+// differs from master.
+type ChecksumDiff struct {
+	Table string
+	Node  string
+	// Lower and Upper are the primary key range [Lower, Upper] of the chunk.
+	// The first chunk also covers keys below Lower and the last chunk keys above Upper.
+	// Both are 0 for a whole table checksum or an empty table on master.
+	Lower int64
+	Upper int64
+	// Master and Replica are the checksums. Empty if the checksum could not be taken.
+	Master  string
+	Replica string
+	// Err is the error of the checksum on the readreplica.
+	Err error
+}
+
+// ChecksumReport is the result of a data drift check.
+// Replication lag also shows up as a difference, so check a quiesced table
+// or re-check differences before acting on them.
+type ChecksumReport struct {
+	Diffs []ChecksumDiff
+}
+
+// DivergedNodes returns the names of readreplicas with any difference.
+func (r *ChecksumReport) DivergedNodes() []string {
+	seen := make(map[string]bool)
+	nodes := make([]string, 0)
+	for i := range r.Diffs {
+		if !seen[r.Diffs[i].Node] {
+			seen[r.Diffs[i].Node] = true
+			nodes = append(nodes, r.Diffs[i].Node)
+		}
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// ChecksumTables compares CHECKSUM TABLE of tables between master and every readreplica.
+func (db *DB) ChecksumTables(ctx context.Context, tables ...string) (*ChecksumReport, error) {
+	quoted := make([]string, len(tables))
+	for i := range tables {
+		quoted[i] = quoteIdent(tables[i])
+	}
+	query := "CHECKSUM TABLE " + strings.Join(quoted, ", ")
+
+	values := db.fanOut(ctx, func(ctx context.Context, n *node) (map[string]string, error) {
+		rows, err := n.db.QueryContext(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		checksums := make(map[string]string)
+		for rows.Next() {
+			var table string
+			var checksum sql.NullString
+			if err := rows.Scan(&table, &checksum); err != nil {
+				return nil, err
+			}
+			checksums[table] = checksum.String
+		}
+		return checksums, rows.Err()
+	})
+
+	if values[0].err != nil {
+		return nil, values[0].err
+	}
+
+	report := &ChecksumReport{}
+	master := values[0].value
+	for _, v := range values[1:] {
+		for _, table := range sortedKeys(master) {
+			diff := ChecksumDiff{
+				Table:  table,
+				Node:   v.name,
+				Master: master[table],
+				Err:    v.err,
+			}
+			if v.err == nil {
+				diff.Replica = v.value[table]
+				if diff.Replica == diff.Master {
+					continue
+				}
+			}
+			report.Diffs = append(report.Diffs, diff)
+		}
+	}
+
+	return report, nil
+}
+
+// ChunkedChecksum configures a checksum by primary key range chunks,
+// in the style of pt-table-checksum.
+type ChunkedChecksum struct {
+	Table string
+	// PrimaryKey is an integer primary key column.
+	PrimaryKey string
+	// Columns are the columns to checksum. All columns of Table if empty.
+	Columns []string
+	// ChunkSize is the number of rows of a chunk. Chunk boundaries are taken from
+	// the primary keys on master, so that gaps in the keys do not make empty chunks.
+	ChunkSize int64
+}
+
+// ChecksumChunks compares the row count and the CRC32 aggregate of each chunk of a table
+// between master and every readreplica.
+func (db *DB) ChecksumChunks(ctx context.Context, c ChunkedChecksum) (*ChecksumReport, error) {
+	if c.ChunkSize <= 0 {
+		return nil, ErrInvalidChunkSize
+	}
+	if db.master == nil {
+		return nil, ErrNilDB
+	}
+
+	columns := c.Columns
+	if len(columns) == 0 {
+		var err error
+		if columns, err = db.tableColumns(ctx, c.Table); err != nil {
+			return nil, err
+		}
+	}
+
+	var min, max sql.NullInt64
+	pk := quoteIdent(c.PrimaryKey)
+	err := db.master.QueryRowContext(ctx,
+		"SELECT MIN("+pk+"), MAX("+pk+") FROM "+quoteIdent(c.Table),
+	).Scan(&min, &max)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first chunk is open below and the last chunk is open above, so that rows
+	// which exist only on a readreplica out of the key range of master are compared too.
+	// An empty table on master is compared as a single chunk.
+	report := &ChecksumReport{}
+	boundaryQuery := chunkBoundaryQuery(c.Table, c.PrimaryKey)
+	lower := min.Int64
+	for first := true; ; first = false {
+		// the last key of the chunk, or max if less than ChunkSize rows are left
+		upper := max.Int64
+		if min.Valid {
+			err := db.master.QueryRowContext(ctx, boundaryQuery, lower, c.ChunkSize-1).Scan(&upper)
+			if err != nil && err != sql.ErrNoRows {
+				return nil, err
+			}
+		}
+		last := upper >= max.Int64
+
+		query := chunkChecksumQuery(c.Table, c.PrimaryKey, columns, first, last)
+		args := make([]interface{}, 0, 2)
+		if !first {
+			args = append(args, lower)
+		}
+		if !last {
+			args = append(args, upper)
+		}
+		values := db.fanOut(ctx, func(ctx context.Context, n *node) (map[string]string, error) {
+			var count int64
+			var crc string
+			if err := n.db.QueryRowContext(ctx, query, args...).Scan(&count, &crc); err != nil {
+				return nil, err
+			}
+			return map[string]string{c.Table: fmt.Sprintf("%d/%s", count, crc)}, nil
+		})
+
+		if values[0].err != nil {
+			return nil, values[0].err
+		}
+
+		master := values[0].value[c.Table]
+		for _, v := range values[1:] {
+			diff := ChecksumDiff{
+				Table:  c.Table,
+				Node:   v.name,
+				Lower:  lower,
+				Upper:  upper,
+				Master: master,
+				Err:    v.err,
+			}
+			if v.err == nil {
+				diff.Replica = v.value[c.Table]
+				if diff.Replica == master {
+					continue
+				}
+			}
+			report.Diffs = append(report.Diffs, diff)
+		}
+
+		// stop at max before upper + 1, which overflows if max is math.MaxInt64
+		if last {
+			break
+		}
+		lower = upper + 1
+	}
+
+	return report, nil
+}
+
+func chunkBoundaryQuery(table, primaryKey string) string {
+	pk := quoteIdent(primaryKey)
+	return "SELECT " + pk + " FROM " + quoteIdent(table) +
+		" WHERE " + pk + " >= ? ORDER BY " + pk + " LIMIT 1 OFFSET ?"
+}
+
+// chunkChecksumQuery returns the checksum query of a chunk. It takes the lower bound
+// unless first and the upper bound unless last as arguments.
+func chunkChecksumQuery(table, primaryKey string, columns []string, first, last bool) string {
+	quoted := make([]string, len(columns))
+	isNull := make([]string, len(columns))
+	for i := range columns {
+		quoted[i] = quoteIdent(columns[i])
+		isNull[i] = "ISNULL(" + quoted[i] + ")"
+	}
+	row := "CONCAT_WS('#', " + strings.Join(quoted, ", ") + ", CONCAT(" + strings.Join(isNull, ", ") + "))"
+	pk := quoteIdent(primaryKey)
+
+	conds := make([]string, 0, 2)
+	if !first {
+		conds = append(conds, pk+" >= ?")
+	}
+	if !last {
+		conds = append(conds, pk+" <= ?")
+	}
+	query := "SELECT COUNT(*), COALESCE(LOWER(CONV(BIT_XOR(CAST(CRC32(" + row + ") AS UNSIGNED)), 10, 16)), '0')" +
+		" FROM " + quoteIdent(table)
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	return query
+}
+
+func (db *DB) tableColumns(ctx context.Context, table string) ([]string, error) {
+	if db.master == nil {
+		return nil, ErrNilDB
+	}
+	rows, err := db.master.QueryContext(ctx,
+		"SELECT COLUMN_NAME FROM information_schema.COLUMNS"+
+			" WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION",
+		table,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make([]string, 0)
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, ErrTableNotFound
+	}
+	return columns, nil
+}
+
+type nodeValue struct {
+	name  string
+	value map[string]string
+	err   error
+}
+
+// fanOut runs fn on master and every readreplica concurrently.
+// Values are ordered master first, then readreplicas.
+func (db *DB) fanOut(ctx context.Context, fn func(ctx context.Context, n *node) (map[string]string, error)) []nodeValue {
+	nodes := db.allNodes()
+	values := make([]nodeValue, len(nodes))
+	goFuncs(len(nodes), func(i int) error {
+		if nodes[i].db == nil {
+			values[i] = nodeValue{name: nodes[i].Name(), err: ErrNilDB}
+			return ErrNilDB
+		}
+		value, err := fn(ctx, nodes[i])
+		values[i] = nodeValue{name: nodes[i].Name(), value: value, err: err}
+		return err
+	})
+	return values
+}
+
+// quoteIdent quotes a possibly schema qualified identifier.
+func quoteIdent(name string) string {
+	parts := strings.Split(name, ".")
+	for i := range parts {
+		parts[i] = "`" + strings.ReplaceAll(parts[i], "`", "``") + "`"
+	}
+	return strings.Join(parts, ".")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
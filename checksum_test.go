@@ -0,0 +1,259 @@
+package mydb
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestChecksumTables(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica0, readreplica0Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1, readreplica1Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		query := regexp.QuoteMeta("CHECKSUM TABLE `code`, `user`")
+		masterMock.ExpectQuery(query).
+			WillReturnRows(sqlmock.NewRows([]string{"Table", "Checksum"}).
+				AddRow("mydb.code", "100").
+				AddRow("mydb.user", "200"))
+		readreplica0Mock.ExpectQuery(query).
+			WillReturnRows(sqlmock.NewRows([]string{"Table", "Checksum"}).
+				AddRow("mydb.code", "100").
+				AddRow("mydb.user", "200"))
+		readreplica1Mock.ExpectQuery(query).
+			WillReturnRows(sqlmock.NewRows([]string{"Table", "Checksum"}).
+				AddRow("mydb.code", "100").
+				AddRow("mydb.user", "201"))
+
+		db := New(master, readreplica0, readreplica1)
+		defer db.Close()
+
+		report, err := db.ChecksumTables(context.Background(), "code", "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []ChecksumDiff{
+			{Table: "mydb.user", Node: "readreplica1", Master: "200", Replica: "201"},
+		}
+		if !reflect.DeepEqual(report.Diffs, want) {
+			t.Errorf("ChecksumTables() want %+v, but get %+v", want, report.Diffs)
+		}
+		if !reflect.DeepEqual(report.DivergedNodes(), []string{"readreplica1"}) {
+			t.Errorf("DivergedNodes() want readreplica1, but get %v", report.DivergedNodes())
+		}
+	})
+
+	t.Run("error with master", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		masterMock.ExpectQuery("CHECKSUM TABLE").
+			WillReturnError(errors.New("query error"))
+
+		db := New(master)
+		defer db.Close()
+
+		if _, err := db.ChecksumTables(context.Background(), "code"); err == nil {
+			t.Error("ChecksumTables() want error")
+		}
+	})
+
+	t.Run("error with nil master", func(t *testing.T) {
+		readreplica, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		db := New(nil, readreplica)
+		defer db.Close()
+
+		if _, err := db.ChecksumTables(context.Background(), "code"); err != ErrNilDB {
+			t.Errorf("ChecksumTables() want %s, but get %v", ErrNilDB, err)
+		}
+		_, err = db.ChecksumChunks(context.Background(), ChunkedChecksum{Table: "code", PrimaryKey: "id", ChunkSize: 10})
+		if err != ErrNilDB {
+			t.Errorf("ChecksumChunks() want %s, but get %v", ErrNilDB, err)
+		}
+	})
+}
+
+func TestChecksumChunks(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, readreplicaMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		masterMock.ExpectQuery(regexp.QuoteMeta("SELECT COLUMN_NAME FROM information_schema.COLUMNS")).
+			WithArgs("code").
+			WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id").AddRow("code"))
+		masterMock.ExpectQuery(regexp.QuoteMeta("SELECT MIN(`id`), MAX(`id`) FROM `code`")).
+			WillReturnRows(sqlmock.NewRows([]string{"min", "max"}).AddRow(1, 15))
+
+		firstQuery := "^" + regexp.QuoteMeta(chunkChecksumQuery("code", "id", []string{"id", "code"}, true, false)) + "$"
+		lastQuery := "^" + regexp.QuoteMeta(chunkChecksumQuery("code", "id", []string{"id", "code"}, false, true)) + "$"
+		boundaryQuery := regexp.QuoteMeta(chunkBoundaryQuery("code", "id"))
+		masterMock.ExpectQuery(boundaryQuery).WithArgs(1, 9).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+		masterMock.ExpectQuery(firstQuery).WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"count", "crc"}).AddRow(10, "abc"))
+		readreplicaMock.ExpectQuery(firstQuery).WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"count", "crc"}).AddRow(10, "abc"))
+		masterMock.ExpectQuery(boundaryQuery).WithArgs(11, 9).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		masterMock.ExpectQuery(lastQuery).WithArgs(11).
+			WillReturnRows(sqlmock.NewRows([]string{"count", "crc"}).AddRow(5, "def"))
+		readreplicaMock.ExpectQuery(lastQuery).WithArgs(11).
+			WillReturnRows(sqlmock.NewRows([]string{"count", "crc"}).AddRow(4, "de0"))
+
+		db := New(master, readreplica)
+		defer db.Close()
+
+		report, err := db.ChecksumChunks(context.Background(), ChunkedChecksum{
+			Table:      "code",
+			PrimaryKey: "id",
+			ChunkSize:  10,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []ChecksumDiff{
+			{Table: "code", Node: "readreplica0", Lower: 11, Upper: 15, Master: "5/def", Replica: "4/de0"},
+		}
+		if !reflect.DeepEqual(report.Diffs, want) {
+			t.Errorf("ChecksumChunks() want %+v, but get %+v", want, report.Diffs)
+		}
+
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+		if err := readreplicaMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("success with max key", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		masterMock.ExpectQuery(regexp.QuoteMeta("SELECT MIN(`id`), MAX(`id`) FROM `code`")).
+			WillReturnRows(sqlmock.NewRows([]string{"min", "max"}).AddRow(int64(math.MaxInt64-1), int64(math.MaxInt64)))
+
+		query := "^" + regexp.QuoteMeta(chunkChecksumQuery("code", "id", []string{"id"}, true, true)) + "$"
+		masterMock.ExpectQuery(regexp.QuoteMeta(chunkBoundaryQuery("code", "id"))).WithArgs(int64(math.MaxInt64-1), 9).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		masterMock.ExpectQuery(query).WithArgs().
+			WillReturnRows(sqlmock.NewRows([]string{"count", "crc"}).AddRow(2, "abc"))
+
+		db := New(master)
+		defer db.Close()
+
+		report, err := db.ChecksumChunks(context.Background(), ChunkedChecksum{
+			Table:      "code",
+			PrimaryKey: "id",
+			Columns:    []string{"id"},
+			ChunkSize:  10,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Diffs) != 0 {
+			t.Errorf("ChecksumChunks() want no diff, but get %+v", report.Diffs)
+		}
+
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("success with empty table on master", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, readreplicaMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		masterMock.ExpectQuery(regexp.QuoteMeta("SELECT MIN(`id`), MAX(`id`) FROM `code`")).
+			WillReturnRows(sqlmock.NewRows([]string{"min", "max"}).AddRow(nil, nil))
+
+		query := "^" + regexp.QuoteMeta(chunkChecksumQuery("code", "id", []string{"id"}, true, true)) + "$"
+		masterMock.ExpectQuery(query).
+			WillReturnRows(sqlmock.NewRows([]string{"count", "crc"}).AddRow(0, "0"))
+		readreplicaMock.ExpectQuery(query).
+			WillReturnRows(sqlmock.NewRows([]string{"count", "crc"}).AddRow(1, "abc"))
+
+		db := New(master, readreplica)
+		defer db.Close()
+
+		report, err := db.ChecksumChunks(context.Background(), ChunkedChecksum{
+			Table:      "code",
+			PrimaryKey: "id",
+			Columns:    []string{"id"},
+			ChunkSize:  10,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []ChecksumDiff{
+			{Table: "code", Node: "readreplica0", Master: "0/0", Replica: "1/abc"},
+		}
+		if !reflect.DeepEqual(report.Diffs, want) {
+			t.Errorf("ChecksumChunks() want %+v, but get %+v", want, report.Diffs)
+		}
+
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+		if err := readreplicaMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("error with chunk size", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		db := New(master)
+		defer db.Close()
+
+		_, err = db.ChecksumChunks(context.Background(), ChunkedChecksum{Table: "code", PrimaryKey: "id"})
+		if err != ErrInvalidChunkSize {
+			t.Errorf("ChecksumChunks() want %s, but get %v", ErrInvalidChunkSize, err)
+		}
+	})
+}
+
+func TestQuoteIdent(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		if quoteIdent("mydb.code") != "`mydb`.`code`" {
+			t.Errorf("quoteIdent() want %s, but get %s", "`mydb`.`code`", quoteIdent("mydb.code"))
+		}
+		if quoteIdent("co`de") != "`co``de`" {
+			t.Errorf("quoteIdent() want %s, but get %s", "`co``de`", quoteIdent("co`de"))
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package mydb
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// connErrorMessages are fragments of errors which mean the connection to the db is lost,
+// for drivers which do not wrap the underlying error.
+var connErrorMessages = []string{
+	"invalid connection",
+	"bad connection",
+	"connection refused",
+	"connection reset",
+	"broken pipe",
+	"Error 2006", // CR_SERVER_GONE_ERROR
+	"Error 2013", // CR_SERVER_LOST
+}
+
+// isConnError reports whether err is a connection level error,
+// as opposed to an error of the statement itself.
+func isConnError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	msg := err.Error()
+	for i := range connErrorMessages {
+		if strings.Contains(msg, connErrorMessages[i]) {
+			return true
+		}
+	}
+	return false
+}
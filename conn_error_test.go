@@ -0,0 +1,34 @@
+package mydb
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestIsConnError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "bad conn", err: driver.ErrBadConn, want: true},
+		{name: "wrapped bad conn", err: fmt.Errorf("query: %w", driver.ErrBadConn), want: true},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, want: true},
+		{name: "lost connection", err: errors.New("Error 2013: Lost connection to MySQL server during query"), want: true},
+		{name: "invalid connection", err: errors.New("invalid connection"), want: true},
+		{name: "syntax error", err: errors.New("Error 1064: You have an error in your SQL syntax"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isConnError(tt.err); got != tt.want {
+				t.Errorf("isConnError(%v) want %t, but get %t", tt.err, tt.want, got)
+			}
+		})
+	}
+}
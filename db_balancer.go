@@ -3,17 +3,50 @@ package mydb
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var _ interface {
 	IsAlive() bool
 	Get() *sql.DB
+	GetContext(ctx context.Context) (*sql.DB, error)
+	GetExcluding(excluded []*sql.DB) *sql.DB
 	Destroy()
 	GetHealthCheckIntervalMilli() int
 	SetHealthCheckIntervalMilli(i int)
+	GetHealthCheckTimeoutMilli() int
+	SetHealthCheckTimeoutMilli(i int)
+	GetHealthCheckRise() int
+	SetHealthCheckRise(n int)
+	GetHealthCheckFall() int
+	SetHealthCheckFall(n int)
+	HealthStates() []HealthState
+	Statuses() []NodeStatus
+	GetOutlierDetection() OutlierDetection
+	SetOutlierDetection(o OutlierDetection)
+	GetCircuitBreaker() CircuitBreaker
+	SetCircuitBreaker(c CircuitBreaker)
 	GetBalanceAlgorithm() BalanceAlgorithm
 	SetBalanceAlgorithm(balanceAlgorithm BalanceAlgorithm)
+	GetAffinityLoadFactor() float64
+	SetAffinityLoadFactor(f float64)
+	GetLocalZone() string
+	SetLocalZone(zone string)
+	SetZone(db *sql.DB, zone string)
+	GetLocalZoneMaxInflight() int64
+	SetLocalZoneMaxInflight(n int64)
+	GetBalancer() Balancer
+	SetBalancer(b Balancer)
+	GetHealthChecker() HealthChecker
+	SetHealthChecker(hc HealthChecker)
+	GetMaxReplicationLag() time.Duration
+	SetMaxReplicationLag(lag time.Duration)
+	GetLogger() Logger
+	SetLogger(l Logger)
+	Ejections() int64
 } = NewDbBalancer(context.Background(), []*sql.DB{})
 
 type BalanceAlgorithm int
@@ -21,26 +54,81 @@ type BalanceAlgorithm int
 const (
 	RoundRobin BalanceAlgorithm = iota
 	Random
+	// P2C (power of two choices) samples two readreplicas at random
+	// and uses the one with fewer outstanding requests.
+	P2C
 )
 
+func (a BalanceAlgorithm) String() string {
+	switch a {
+	case RoundRobin:
+		return "round_robin"
+	case Random:
+		return "random"
+	case P2C:
+		return "p2c"
+	default:
+		return "unknown"
+	}
+}
+
 type dbBalancer struct {
 	ctx                      context.Context
 	cancel                   context.CancelFunc
 	dbs                      []*sql.DB
+	nodes                    map[*sql.DB]*node
+	ring                     *hashRing
 	availableDbs             *dbList
+	localDbs                 *dbList
 	isMulti                  bool
 	healthCheckIntervalMilli int
 	balanceAlgorithm         BalanceAlgorithm
+	affinityLoadFactor       float64
+	events                   *eventHub
+	ejections                int64
+
+	// refreshLk serializes rebuilding the available dbs
+	refreshLk sync.Mutex
+	fallback  bool
+
+	lk                      sync.RWMutex
+	localZone               string
+	localZoneMaxInflight    int64
+	balancer                Balancer
+	healthChecker           HealthChecker
+	healthCheckTimeoutMilli int
+	healthCheckRise         int
+	healthCheckFall         int
+	outlierDetection        OutlierDetection
+	circuitBreaker          CircuitBreaker
+	maxReplicationLag       time.Duration
+	logger                  Logger
 }
 
 func NewDbBalancer(ctx context.Context, dbs []*sql.DB) *dbBalancer {
 	d := &dbBalancer{
 		dbs:                      dbs,
+		nodes:                    make(map[*sql.DB]*node, len(dbs)),
 		availableDbs:             NewDbList(),
+		localDbs:                 NewDbList(),
 		isMulti:                  len(dbs) > 1,
 		healthCheckIntervalMilli: DefaultHealthCheckIntervalMilli,
 		balanceAlgorithm:         DefaultBalanceAlgorithm,
+		affinityLoadFactor:       DefaultAffinityLoadFactor,
+		events:                   newEventHub(),
+		logger:                   NopLogger(),
+		healthChecker:            PingChecker(),
+		healthCheckTimeoutMilli:  DefaultHealthCheckTimeoutMilli,
+		healthCheckRise:          DefaultHealthCheckRise,
+		healthCheckFall:          DefaultHealthCheckFall,
+	}
+
+	nodes := make([]*node, len(dbs))
+	for i := range dbs {
+		nodes[i] = newNode(ReadReplica, fmt.Sprintf("readreplica%d", i), dbs[i])
+		d.nodes[dbs[i]] = nodes[i]
 	}
+	d.ring = newHashRing(nodes)
 
 	// setup context
 	d.ctx, d.cancel = context.WithCancel(ctx)
@@ -55,17 +143,108 @@ func NewDbBalancer(ctx context.Context, dbs []*sql.DB) *dbBalancer {
 }
 
 func (d *dbBalancer) healthCheck() {
+	healthChecker := d.GetHealthChecker()
+	timeout := time.Duration(d.GetHealthCheckTimeoutMilli()) * time.Millisecond
+
+	// check all dbs concurrently, so that one stalled db does not delay the others
+	results := make([]probeResult, len(d.dbs))
+	goFuncs(len(d.dbs), func(i int) error {
+		results[i] = probe(d.ctx, healthChecker, d.dbs[i], timeout)
+		return nil
+	})
+
+	rise, fall, maxLag := d.GetHealthCheckRise(), d.GetHealthCheckFall(), d.GetMaxReplicationLag()
+	for i := range d.dbs {
+		n := d.nodes[d.dbs[i]]
+		if err := results[i].err; err != nil {
+			d.GetLogger().Warn("health check failed", "node", n.Name(), "role", n.Role().String(), "err", err)
+		}
+		d.emit(n.observeProbe(results[i], rise, fall, maxLag)...)
+		n.resetOutlierWindow()
+	}
+
+	d.refreshAvailableDbs()
+}
+
+// refreshAvailableDbs rebuilds the available dbs from the state of each node.
+// A db is available if it is healthy, not ejected and its circuit breaker is not open.
+// FallbackActivated or FallbackCleared is emitted when no db or some db becomes available.
+func (d *dbBalancer) refreshAvailableDbs() {
+	d.refreshLk.Lock()
+
+	now := time.Now()
+	c := d.GetCircuitBreaker()
+
 	// OPTIMIZE: allocate times
-	// Not critical, Because this method called by only health check.
+	// Not critical, Because this method called by only health check and ejection.
 	availableDbs := make([]*sql.DB, 0)
 	for i := range d.dbs {
-		db := d.dbs[i]
-		if db.Ping() == nil {
-			availableDbs = append(availableDbs, db)
+		n := d.nodes[d.dbs[i]]
+		if n.isHealthy() && !n.isEjected(now) && n.breaker.State(c, now) != BreakerOpen {
+			availableDbs = append(availableDbs, d.dbs[i])
 		}
 	}
 
 	d.availableDbs.Replace(availableDbs)
+	d.refreshLocalDbs()
+
+	var events []Event
+	if fallback := len(availableDbs) == 0; fallback != d.fallback && len(d.dbs) > 0 {
+		d.fallback = fallback
+		e := Event{Type: FallbackCleared, Time: now}
+		if fallback {
+			e.Type = FallbackActivated
+		}
+		events = append(events, e)
+	}
+	d.refreshLk.Unlock()
+
+	// emitted without the lock, so that handlers can change the configuration
+	d.emit(events...)
+}
+
+// refreshLocalDbs rebuilds the available dbs in the local zone.
+func (d *dbBalancer) refreshLocalDbs() {
+	d.lk.RLock()
+	localZone := d.localZone
+	d.lk.RUnlock()
+
+	localDbs := make([]*sql.DB, 0)
+	if localZone != "" {
+		availableDbs := d.availableDbs.List()
+		for i := range availableDbs {
+			if d.nodes[availableDbs[i]].Zone() == localZone {
+				localDbs = append(localDbs, availableDbs[i])
+			}
+		}
+	}
+
+	d.localDbs.Replace(localDbs)
+}
+
+// candidates returns the dbs to balance over.
+// Dbs in the local zone are preferred, and dbs in remote zones are used
+// only when no local db is available or all local dbs are saturated.
+func (d *dbBalancer) candidates() *dbList {
+	if d.localDbs.IsEmpty() || d.isSaturated(d.localDbs) {
+		return d.availableDbs
+	}
+	return d.localDbs
+}
+
+func (d *dbBalancer) isSaturated(list *dbList) bool {
+	max := d.GetLocalZoneMaxInflight()
+	if max <= 0 {
+		return false
+	}
+
+	dbs := list.List()
+	for i := range dbs {
+		if d.nodes[dbs[i]].load() < max {
+			return false
+		}
+	}
+	return true
 }
 
 func (d *dbBalancer) healthCheckWorker() {
@@ -85,18 +264,167 @@ func (d *dbBalancer) IsAlive() bool {
 }
 
 func (d *dbBalancer) Get() *sql.DB {
-	if d.availableDbs.IsEmpty() {
-		return nil
+	db, _ := d.GetContext(context.Background())
+	return db
+}
+
+// GetContext returns a readreplica for ctx.
+// A registered Balancer takes precedence. Otherwise, if ctx carries an affinity key,
+// the readreplica is chosen by consistent hashing, else by the balance algorithm.
+func (d *dbBalancer) GetContext(ctx context.Context) (*sql.DB, error) {
+	list := d.candidates()
+	if list.IsEmpty() {
+		return nil, ErrAllReadreplicaDied
+	}
+
+	if b := d.GetBalancer(); b != nil {
+		db, err := d.pick(ctx, b, list)
+		if err != nil {
+			return nil, err
+		}
+		return d.admit(db, list)
+	}
+
+	if key, ok := AffinityKeyFromContext(ctx); ok {
+		return d.admit(d.affinity(key, list), list)
 	}
 
 	switch d.balanceAlgorithm {
 	case RoundRobin:
-		return d.availableDbs.Next()
+		return d.admit(list.Next(), list)
 	case Random:
-		return d.availableDbs.Random()
+		return d.admit(list.Random(), list)
+	case P2C:
+		return d.admit(d.p2c(list), list)
 	default:
+		return nil, ErrUnknownBalanceAlgorithm
+	}
+}
+
+// decision describes how GetContext chooses a readreplica for ctx:
+// the way of choosing and whether only dbs in the local zone are candidates.
+func (d *dbBalancer) decision(ctx context.Context) (string, bool) {
+	localZone := d.candidates() == d.localDbs
+
+	if d.GetBalancer() != nil {
+		return "balancer", localZone
+	}
+	if _, ok := AffinityKeyFromContext(ctx); ok {
+		return "affinity", localZone
+	}
+	return d.balanceAlgorithm.String(), localZone
+}
+
+// admit checks the circuit breaker of db. If db is not allowed,
+// e.g. its half-open breaker has no trial left, another db in list is used,
+// and then any available db, e.g. in a remote zone.
+func (d *dbBalancer) admit(db *sql.DB, list *dbList) (*sql.DB, error) {
+	c := d.GetCircuitBreaker()
+	if !c.enabled() {
+		return db, nil
+	}
+
+	now := time.Now()
+	if db != nil && d.nodes[db].breaker.allow(c, now) {
+		return db, nil
+	}
+
+	dbs := list.List()
+	if list != d.availableDbs {
+		dbs = append(dbs, d.availableDbs.List()...)
+	}
+	for i := range dbs {
+		if dbs[i] != db && d.nodes[dbs[i]].breaker.allow(c, now) {
+			return dbs[i], nil
+		}
+	}
+	return nil, ErrCircuitOpen
+}
+
+// GetExcluding returns an available db which is not in excluded, or nil.
+// It only reads the circuit breakers, so the caller must take the trial of
+// a half-open breaker with allow before calling the db.
+func (d *dbBalancer) GetExcluding(excluded []*sql.DB) *sql.DB {
+	c := d.GetCircuitBreaker()
+	now := time.Now()
+
+	// prefer the local zone
+	dbs := append(d.localDbs.List(), d.availableDbs.List()...)
+
+next:
+	for i := range dbs {
+		for j := range excluded {
+			if dbs[i] == excluded[j] {
+				continue next
+			}
+		}
+		if d.nodes[dbs[i]].breaker.admits(c, now) {
+			return dbs[i]
+		}
+	}
+	return nil
+}
+
+func (d *dbBalancer) affinity(key string, list *dbList) *sql.DB {
+	dbs := list.List()
+	available := make([]*node, len(dbs))
+	for i := range dbs {
+		available[i] = d.nodes[dbs[i]]
+	}
+
+	n := d.ring.Get(key, available, d.affinityLoadFactor)
+	if n == nil {
 		return nil
 	}
+	return n.db
+}
+
+func (d *dbBalancer) p2c(list *dbList) *sql.DB {
+	a, b := list.RandomPair()
+	if a == b {
+		return a
+	}
+
+	if d.nodes[b].lessLoaded(d.nodes[a]) {
+		return b
+	}
+	return a
+}
+
+// track marks the start of a call on db and returns the function
+// which marks its end with the call result.
+// Calls on dbs not owned by the balancer are ignored.
+func (d *dbBalancer) track(db *sql.DB) func(err error) {
+	n, ok := d.nodes[db]
+	if !ok {
+		return func(err error) {}
+	}
+
+	start := n.begin()
+	return func(err error) {
+		n.end(start)
+		now := time.Now()
+
+		if o := d.GetOutlierDetection(); o.enabled() && n.observeOutlier(err, o, now) {
+			atomic.AddInt64(&d.ejections, 1)
+			d.GetLogger().Warn("readreplica ejected", "node", n.Name(), "err", err, "duration", o.ejectionTime())
+			d.refreshAvailableDbs()
+		}
+
+		if c := d.GetCircuitBreaker(); n.breaker.observe(c, err, now.Sub(start), now) {
+			d.GetLogger().Warn("circuit breaker opened", "node", n.Name(), "err", err, "duration", c.openTimeout())
+			d.refreshAvailableDbs()
+			// bring the db back as half-open after the open timeout
+			time.AfterFunc(c.openTimeout(), d.refreshAvailableDbs)
+		}
+
+		if b := d.GetBalancer(); b != nil {
+			b.ObserveLatency(n, time.Since(start))
+			if err != nil {
+				b.ObserveError(n, err)
+			}
+		}
+	}
 }
 
 func (d *dbBalancer) Destroy() {
@@ -111,6 +439,113 @@ func (d *dbBalancer) SetHealthCheckIntervalMilli(i int) {
 	d.healthCheckIntervalMilli = i
 }
 
+func (d *dbBalancer) GetHealthCheckTimeoutMilli() int {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+
+	return d.healthCheckTimeoutMilli
+}
+
+func (d *dbBalancer) SetHealthCheckTimeoutMilli(i int) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	d.healthCheckTimeoutMilli = i
+}
+
+func (d *dbBalancer) GetHealthCheckRise() int {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+
+	return d.healthCheckRise
+}
+
+func (d *dbBalancer) SetHealthCheckRise(n int) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	d.healthCheckRise = n
+}
+
+func (d *dbBalancer) GetHealthCheckFall() int {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+
+	return d.healthCheckFall
+}
+
+func (d *dbBalancer) SetHealthCheckFall(n int) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	d.healthCheckFall = n
+}
+
+// nodeList returns the nodes of all dbs in order.
+func (d *dbBalancer) nodeList() []*node {
+	nodes := make([]*node, len(d.dbs))
+	for i := range d.dbs {
+		nodes[i] = d.nodes[d.dbs[i]]
+	}
+	return nodes
+}
+
+// HealthStates returns the health states of all dbs.
+func (d *dbBalancer) HealthStates() []HealthState {
+	nodes := d.nodeList()
+	states := make([]HealthState, len(nodes))
+	for i := range nodes {
+		states[i] = nodes[i].healthState(d.GetCircuitBreaker())
+	}
+	return states
+}
+
+// Statuses returns the statuses of all dbs.
+func (d *dbBalancer) Statuses() []NodeStatus {
+	available := make(map[*sql.DB]bool)
+	for _, db := range d.availableDbs.List() {
+		available[db] = true
+	}
+
+	now := time.Now()
+	nodes := d.nodeList()
+	statuses := make([]NodeStatus, len(nodes))
+	for i := range nodes {
+		statuses[i] = nodes[i].status(d.GetCircuitBreaker(), now)
+		statuses[i].Available = available[nodes[i].db]
+	}
+	return statuses
+}
+
+func (d *dbBalancer) GetOutlierDetection() OutlierDetection {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+
+	return d.outlierDetection
+}
+
+func (d *dbBalancer) SetOutlierDetection(o OutlierDetection) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	d.outlierDetection = o
+}
+
+func (d *dbBalancer) GetCircuitBreaker() CircuitBreaker {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+
+	return d.circuitBreaker
+}
+
+func (d *dbBalancer) SetCircuitBreaker(c CircuitBreaker) {
+	d.lk.Lock()
+	d.circuitBreaker = c
+	d.lk.Unlock()
+
+	d.refreshAvailableDbs()
+}
+
 func (d *dbBalancer) GetBalanceAlgorithm() BalanceAlgorithm {
 	return d.balanceAlgorithm
 }
@@ -118,3 +553,128 @@ func (d *dbBalancer) GetBalanceAlgorithm() BalanceAlgorithm {
 func (d *dbBalancer) SetBalanceAlgorithm(balanceAlgorithm BalanceAlgorithm) {
 	d.balanceAlgorithm = balanceAlgorithm
 }
+
+func (d *dbBalancer) GetAffinityLoadFactor() float64 {
+	return d.affinityLoadFactor
+}
+
+func (d *dbBalancer) SetAffinityLoadFactor(f float64) {
+	d.affinityLoadFactor = f
+}
+
+func (d *dbBalancer) GetLocalZone() string {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+
+	return d.localZone
+}
+
+func (d *dbBalancer) SetLocalZone(zone string) {
+	d.lk.Lock()
+	d.localZone = zone
+	d.lk.Unlock()
+
+	d.refreshLocalDbs()
+}
+
+// SetZone sets the zone label of db. Dbs not owned by the balancer are ignored.
+func (d *dbBalancer) SetZone(db *sql.DB, zone string) {
+	n, ok := d.nodes[db]
+	if !ok {
+		return
+	}
+	n.setZone(zone)
+
+	d.refreshLocalDbs()
+}
+
+func (d *dbBalancer) GetLocalZoneMaxInflight() int64 {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+
+	return d.localZoneMaxInflight
+}
+
+func (d *dbBalancer) SetLocalZoneMaxInflight(n int64) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	d.localZoneMaxInflight = n
+}
+
+func (d *dbBalancer) GetBalancer() Balancer {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+
+	return d.balancer
+}
+
+// SetBalancer registers a custom Balancer. Passing nil restores the balance algorithm.
+func (d *dbBalancer) SetBalancer(b Balancer) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	d.balancer = b
+}
+
+func (d *dbBalancer) GetHealthChecker() HealthChecker {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+
+	return d.healthChecker
+}
+
+func (d *dbBalancer) SetHealthChecker(hc HealthChecker) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	d.healthChecker = hc
+}
+
+func (d *dbBalancer) GetMaxReplicationLag() time.Duration {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+
+	return d.maxReplicationLag
+}
+
+// SetMaxReplicationLag sets the replication lag over which LagExceeded is emitted. 0 disables it.
+func (d *dbBalancer) SetMaxReplicationLag(lag time.Duration) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	d.maxReplicationLag = lag
+}
+
+// emit logs events and delivers them to handlers and watchers.
+func (d *dbBalancer) emit(events ...Event) {
+	l := d.GetLogger()
+	for i := range events {
+		logEvent(l, events[i])
+	}
+	d.events.emit(events...)
+}
+
+func (d *dbBalancer) GetLogger() Logger {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+
+	return d.logger
+}
+
+// SetLogger sets the logger. nil discards all messages.
+func (d *dbBalancer) SetLogger(l Logger) {
+	if l == nil {
+		l = NopLogger()
+	}
+
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	d.logger = l
+}
+
+// Ejections returns the number of dbs ejected by outlier detection.
+func (d *dbBalancer) Ejections() int64 {
+	return atomic.LoadInt64(&d.ejections)
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 )
@@ -83,6 +84,133 @@ func TestGet(t *testing.T) {
 	})
 }
 
+func TestGetWithP2C(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db0, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		db1, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		dbBalancer := NewDbBalancer(context.Background(), []*sql.DB{db0, db1})
+		defer dbBalancer.Destroy()
+		dbBalancer.SetBalanceAlgorithm(P2C)
+
+		// db0 has an outstanding request
+		done := dbBalancer.track(db0)
+		for i := 0; i < 10; i++ {
+			if !reflect.DeepEqual(dbBalancer.Get(), db1) {
+				t.Error("dbBalancer Get() want db1")
+			}
+		}
+		done(nil)
+
+		if dbBalancer.nodes[db0].Inflight() != 0 {
+			t.Error("Inflight() want 0")
+		}
+	})
+}
+
+func TestGetWithLocalZone(t *testing.T) {
+	newDbs := func() []*sql.DB {
+		dbs := make([]*sql.DB, 3)
+		for i := range dbs {
+			db, _, err := sqlmock.New()
+			if err != nil {
+				t.Error(err.Error())
+			}
+			dbs[i] = db
+		}
+		return dbs
+	}
+
+	t.Run("success with local zone", func(t *testing.T) {
+		dbs := newDbs()
+		dbBalancer := NewDbBalancer(context.Background(), dbs)
+		defer dbBalancer.Destroy()
+		dbBalancer.SetZone(dbs[0], "zone-a")
+		dbBalancer.SetZone(dbs[1], "zone-a")
+		dbBalancer.SetZone(dbs[2], "zone-b")
+		dbBalancer.SetLocalZone("zone-b")
+
+		for i := 0; i < 10; i++ {
+			if dbBalancer.Get() != dbs[2] {
+				t.Error("dbBalancer Get() want db in local zone")
+			}
+		}
+	})
+
+	t.Run("success with no db in local zone", func(t *testing.T) {
+		dbs := newDbs()
+		dbBalancer := NewDbBalancer(context.Background(), dbs)
+		defer dbBalancer.Destroy()
+		dbBalancer.SetLocalZone("zone-c")
+
+		if dbBalancer.Get() == nil {
+			t.Error("dbBalancer Get() want db in remote zone")
+		}
+	})
+
+	t.Run("success with saturated local zone", func(t *testing.T) {
+		dbs := newDbs()
+		dbBalancer := NewDbBalancer(context.Background(), dbs)
+		defer dbBalancer.Destroy()
+		dbBalancer.SetBalanceAlgorithm(P2C)
+		dbBalancer.SetZone(dbs[2], "zone-b")
+		dbBalancer.SetLocalZone("zone-b")
+		dbBalancer.SetLocalZoneMaxInflight(1)
+
+		done := dbBalancer.track(dbs[2])
+		defer done(nil)
+
+		for i := 0; i < 10; i++ {
+			if dbBalancer.Get() == dbs[2] {
+				t.Error("dbBalancer Get() want db in remote zone")
+			}
+		}
+	})
+}
+
+func TestHealthCheckWithTimeout(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db0, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		db1, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		dbBalancer := NewDbBalancer(context.Background(), []*sql.DB{db0, db1})
+		defer dbBalancer.Destroy()
+
+		// db0 is blackholed
+		block := make(chan struct{})
+		defer close(block)
+		dbBalancer.SetHealthCheckTimeoutMilli(10)
+		dbBalancer.SetHealthChecker(HealthCheckerFunc(func(ctx context.Context, db *sql.DB) error {
+			if db == db0 {
+				<-block
+			}
+			return nil
+		}))
+
+		start := time.Now()
+		dbBalancer.healthCheck()
+		if time.Since(start) > time.Second {
+			t.Error("healthCheck() want to return at deadline")
+		}
+
+		for i := 0; i < 10; i++ {
+			if dbBalancer.Get() != db1 {
+				t.Error("dbBalancer Get() want db1")
+			}
+		}
+	})
+}
+
 func TestSetHealthCheckIntervalMilli(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		dbBalancer := NewDbBalancer(context.Background(), nil)
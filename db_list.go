@@ -13,6 +13,8 @@ var _ interface {
 	Current() *sql.DB
 	Next() *sql.DB
 	Random() *sql.DB
+	RandomPair() (*sql.DB, *sql.DB)
+	List() []*sql.DB
 	Replace(dbs []*sql.DB)
 } = NewDbList()
 
@@ -69,6 +71,44 @@ func (d *dbList) Random() (res *sql.DB) {
 	return
 }
 
+// RandomPair returns two distinct dbs chosen at random.
+// If the list has only one db, both results are the same db.
+func (d *dbList) RandomPair() (a, b *sql.DB) {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+
+	len := len(d.list)
+	switch len {
+	case 0:
+		return
+	case 1:
+		a = d.list[0]
+		b = d.list[0]
+		return
+	}
+
+	i := rand.Intn(len)
+	j := rand.Intn(len - 1)
+	if j >= i {
+		j++
+	}
+	a = d.list[i]
+	b = d.list[j]
+
+	return
+}
+
+// List returns a copy of the current dbs.
+func (d *dbList) List() []*sql.DB {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+
+	res := make([]*sql.DB, len(d.list))
+	copy(res, d.list)
+
+	return res
+}
+
 func (d *dbList) isSame(dbs []*sql.DB) bool {
 	if len(d.list) != len(dbs) {
 		return false
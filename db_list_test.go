@@ -119,6 +119,71 @@ func TestRandom(t *testing.T) {
 	})
 }
 
+func TestRandomPair(t *testing.T) {
+	t.Run("success empty", func(t *testing.T) {
+		dbs := NewDbList()
+		a, b := dbs.RandomPair()
+		if a != nil || b != nil {
+			t.Errorf("RandomPair() want nil, but not nil")
+		}
+	})
+
+	t.Run("success single", func(t *testing.T) {
+		db0, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		dbs := dbList{
+			list: []*sql.DB{db0},
+		}
+		a, b := dbs.RandomPair()
+		if a != db0 || b != db0 {
+			t.Errorf("RandomPair() want db0 and db0")
+		}
+	})
+
+	t.Run("success distinct", func(t *testing.T) {
+		db0, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		db1, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		dbs := dbList{
+			list: []*sql.DB{db0, db1},
+		}
+		for i := 0; i < 10; i++ {
+			a, b := dbs.RandomPair()
+			if a == b {
+				t.Errorf("RandomPair() want distinct dbs")
+			}
+		}
+	})
+}
+
+func TestList(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db0, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		dbs := dbList{
+			list: []*sql.DB{db0},
+		}
+		list := dbs.List()
+		if len(list) != 1 || list[0] != db0 {
+			t.Error("List() want db0")
+		}
+
+		list[0] = nil
+		if dbs.list[0] != db0 {
+			t.Error("List() want copy")
+		}
+	})
+}
+
 func TestReplace(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		db0, _, err := sqlmock.New()
@@ -0,0 +1,174 @@
+package mydb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// DefaultDriftVariables are the server variables compared by CheckVariableDrift by default.
+var DefaultDriftVariables = []string{
+	"sql_mode",
+	"time_zone",
+	"character_set_server",
+	"collation_server",
+	"max_allowed_packet",
+}
+
+// Drift is a difference between master and a readreplica.
+type Drift struct {
+	Node string
+	// Kind is "table", "column", "index" or "variable".
+	Kind string
+	// Object is the name of the table, column (table.column), index (table.index) or variable.
+	Object string
+	// Master and Replica are the definitions or values. Empty if missing.
+	Master  string
+	Replica string
+	// Err is the error of the check on the readreplica.
+	Err error
+}
+
+// DriftReport is the result of a drift check.
+type DriftReport struct {
+	Drifts []Drift
+}
+
+// CheckSchemaDrift compares tables, columns and indexes in information_schema
+// of the current database between master and every readreplica.
+func (db *DB) CheckSchemaDrift(ctx context.Context) (*DriftReport, error) {
+	return db.checkDrift(ctx, func(ctx context.Context, n *node) (map[string]string, error) {
+		definitions := make(map[string]string)
+		for _, q := range schemaQueries {
+			if err := collectDefinitions(ctx, n.db, q.kind, q.query, definitions); err != nil {
+				return nil, err
+			}
+		}
+		return definitions, nil
+	})
+}
+
+// CheckVariableDrift compares global server variables between master and every readreplica.
+// DefaultDriftVariables are compared if variables is empty.
+func (db *DB) CheckVariableDrift(ctx context.Context, variables ...string) (*DriftReport, error) {
+	if len(variables) == 0 {
+		variables = DefaultDriftVariables
+	}
+	wanted := make(map[string]bool, len(variables))
+	for i := range variables {
+		wanted[strings.ToLower(variables[i])] = true
+	}
+
+	return db.checkDrift(ctx, func(ctx context.Context, n *node) (map[string]string, error) {
+		rows, err := n.db.QueryContext(ctx, "SHOW GLOBAL VARIABLES")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		values := make(map[string]string)
+		for rows.Next() {
+			var name, value string
+			if err := rows.Scan(&name, &value); err != nil {
+				return nil, err
+			}
+			if wanted[strings.ToLower(name)] {
+				values[driftKey("variable", name)] = value
+			}
+		}
+		return values, rows.Err()
+	})
+}
+
+var schemaQueries = []struct {
+	kind  string
+	query string
+}{
+	{
+		kind: "table",
+		query: "SELECT TABLE_NAME, CONCAT_WS(' ', ENGINE, TABLE_COLLATION)" +
+			" FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE()",
+	},
+	{
+		kind: "column",
+		query: "SELECT CONCAT(TABLE_NAME, '.', COLUMN_NAME)," +
+			" CONCAT_WS(' ', ORDINAL_POSITION, COLUMN_TYPE, IS_NULLABLE, COALESCE(COLUMN_DEFAULT, 'NULL'), COLLATION_NAME, EXTRA)" +
+			" FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE()",
+	},
+	{
+		kind: "index",
+		query: "SELECT CONCAT(TABLE_NAME, '.', INDEX_NAME)," +
+			" CONCAT_WS(' ', NON_UNIQUE, INDEX_TYPE, GROUP_CONCAT(COLUMN_NAME ORDER BY SEQ_IN_INDEX))" +
+			" FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE()" +
+			" GROUP BY TABLE_NAME, INDEX_NAME, NON_UNIQUE, INDEX_TYPE",
+	},
+}
+
+func collectDefinitions(ctx context.Context, db *sql.DB, kind, query string, definitions map[string]string) error {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var object, definition string
+		if err := rows.Scan(&object, &definition); err != nil {
+			return err
+		}
+		definitions[driftKey(kind, object)] = definition
+	}
+	return rows.Err()
+}
+
+func (db *DB) checkDrift(ctx context.Context, fn func(ctx context.Context, n *node) (map[string]string, error)) (*DriftReport, error) {
+	values := db.fanOut(ctx, fn)
+	if values[0].err != nil {
+		return nil, values[0].err
+	}
+
+	report := &DriftReport{}
+	master := values[0].value
+	for _, v := range values[1:] {
+		if v.err != nil {
+			report.Drifts = append(report.Drifts, Drift{Node: v.name, Err: v.err})
+			continue
+		}
+
+		keys := make(map[string]string, len(master))
+		for k := range master {
+			keys[k] = ""
+		}
+		for k := range v.value {
+			keys[k] = ""
+		}
+
+		for _, key := range sortedKeys(keys) {
+			masterValue, onMaster := master[key]
+			replicaValue, onReplica := v.value[key]
+			if onMaster == onReplica && masterValue == replicaValue {
+				continue
+			}
+			kind, object := splitDriftKey(key)
+			report.Drifts = append(report.Drifts, Drift{
+				Node:    v.name,
+				Kind:    kind,
+				Object:  object,
+				Master:  masterValue,
+				Replica: replicaValue,
+			})
+		}
+	}
+
+	return report, nil
+}
+
+func driftKey(kind, object string) string {
+	return fmt.Sprintf("%s %s", kind, object)
+}
+
+func splitDriftKey(key string) (kind, object string) {
+	parts := strings.SplitN(key, " ", 2)
+	return parts[0], parts[1]
+}
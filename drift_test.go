@@ -0,0 +1,128 @@
+package mydb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCheckSchemaDrift(t *testing.T) {
+	expectSchema := func(mock sqlmock.Sqlmock, columnType string, withIndex bool) {
+		mock.ExpectQuery("FROM information_schema.TABLES").
+			WillReturnRows(sqlmock.NewRows([]string{"object", "definition"}).
+				AddRow("code", "InnoDB utf8mb4_general_ci"))
+		mock.ExpectQuery("FROM information_schema.COLUMNS").
+			WillReturnRows(sqlmock.NewRows([]string{"object", "definition"}).
+				AddRow("code.code", "1 "+columnType+" YES NULL"))
+		indexes := sqlmock.NewRows([]string{"object", "definition"})
+		if withIndex {
+			indexes.AddRow("code.idx_code", "1 BTREE code")
+		}
+		mock.ExpectQuery("FROM information_schema.STATISTICS").
+			WillReturnRows(indexes)
+	}
+
+	t.Run("success", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica0, readreplica0Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1, readreplica1Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		expectSchema(masterMock, "bigint", true)
+		expectSchema(readreplica0Mock, "bigint", true)
+		// readreplica1 missed DDLs
+		expectSchema(readreplica1Mock, "int", false)
+
+		db := New(master, readreplica0, readreplica1)
+		defer db.Close()
+
+		report, err := db.CheckSchemaDrift(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []Drift{
+			{Node: "readreplica1", Kind: "column", Object: "code.code", Master: "1 bigint YES NULL", Replica: "1 int YES NULL"},
+			{Node: "readreplica1", Kind: "index", Object: "code.idx_code", Master: "1 BTREE code"},
+		}
+		if !reflect.DeepEqual(report.Drifts, want) {
+			t.Errorf("CheckSchemaDrift() want %+v, but get %+v", want, report.Drifts)
+		}
+	})
+}
+
+func TestCheckVariableDrift(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, readreplicaMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		masterMock.ExpectQuery("SHOW GLOBAL VARIABLES").
+			WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).
+				AddRow("sql_mode", "STRICT_TRANS_TABLES").
+				AddRow("time_zone", "SYSTEM").
+				AddRow("version", "8.0.27"))
+		readreplicaMock.ExpectQuery("SHOW GLOBAL VARIABLES").
+			WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).
+				AddRow("sql_mode", "").
+				AddRow("time_zone", "SYSTEM").
+				AddRow("version", "8.0.26"))
+
+		db := New(master, readreplica)
+		defer db.Close()
+
+		report, err := db.CheckVariableDrift(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []Drift{
+			{Node: "readreplica0", Kind: "variable", Object: "sql_mode", Master: "STRICT_TRANS_TABLES", Replica: ""},
+		}
+		if !reflect.DeepEqual(report.Drifts, want) {
+			t.Errorf("CheckVariableDrift() want %+v, but get %+v", want, report.Drifts)
+		}
+	})
+
+	t.Run("success with readreplica error", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, readreplicaMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		masterMock.ExpectQuery("SHOW GLOBAL VARIABLES").
+			WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).
+				AddRow("sql_mode", "STRICT_TRANS_TABLES"))
+		queryErr := errors.New("query error")
+		readreplicaMock.ExpectQuery("SHOW GLOBAL VARIABLES").
+			WillReturnError(queryErr)
+
+		db := New(master, readreplica)
+		defer db.Close()
+
+		report, err := db.CheckVariableDrift(context.Background(), "sql_mode")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Drifts) != 1 || report.Drifts[0].Err != queryErr {
+			t.Errorf("CheckVariableDrift() want error of readreplica0, but get %+v", report.Drifts)
+		}
+	})
+}
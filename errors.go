@@ -3,6 +3,17 @@ package mydb
 import "errors"
 
 var (
-	ErrAllReadreplicaDied = errors.New("all readreadreplica died")
-	ErrMasterDied         = errors.New("master died")
+	ErrAllReadreplicaDied      = errors.New("all readreadreplica died")
+	ErrMasterDied              = errors.New("master died")
+	ErrUnknownBalanceAlgorithm = errors.New("unknown balance algorithm")
+	ErrNotReplica              = errors.New("not a replica")
+	ErrReplicationStopped      = errors.New("replication stopped")
+	ErrUnexpectedReadOnly      = errors.New("unexpected read_only")
+	ErrCircuitOpen             = errors.New("circuit breaker open")
+	ErrInvalidChunkSize        = errors.New("invalid chunk size")
+	ErrTableNotFound           = errors.New("table not found")
+	ErrTooFewReadreplicas      = errors.New("too few readreplicas")
+	ErrNilResult               = errors.New("nil result")
+	ErrUnknownNode             = errors.New("unknown node")
+	ErrNilDB                   = errors.New("nil db")
 )
@@ -0,0 +1,140 @@
+package mydb
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultEventBufferSize is the buffer size of the channel returned by Watch.
+const DefaultEventBufferSize = 64
+
+type EventType int
+
+const (
+	// NodeDown is emitted when a healthy node becomes unhealthy.
+	NodeDown EventType = iota
+	// NodeUp is emitted when an unhealthy node becomes healthy.
+	NodeUp
+	// LagExceeded is emitted when the replication lag of a readreplica exceeds the max replication lag.
+	LagExceeded
+	// FallbackActivated is emitted when no readreplica becomes available.
+	FallbackActivated
+	// FallbackCleared is emitted when a readreplica becomes available again.
+	FallbackCleared
+	// MasterChanged is emitted when the identity of master changes, e.g. by a failover.
+	MasterChanged
+)
+
+func (t EventType) String() string {
+	switch t {
+	case NodeDown:
+		return "node_down"
+	case NodeUp:
+		return "node_up"
+	case LagExceeded:
+		return "lag_exceeded"
+	case FallbackActivated:
+		return "fallback_activated"
+	case FallbackCleared:
+		return "fallback_cleared"
+	case MasterChanged:
+		return "master_changed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change of the cluster topology.
+type Event struct {
+	Type EventType
+	Time time.Time
+	// Node is empty for fallback events, and Role is then the zero value Master
+	// without meaning a node. Check Node before Role.
+	Node string
+	Role Role
+	// Err is the health check error of NodeDown.
+	Err error
+	// Lag is the replication lag of LagExceeded.
+	Lag time.Duration
+	// Identity and PreviousIdentity are the identities of master of MasterChanged.
+	Identity         string
+	PreviousIdentity string
+}
+
+var _ interface {
+	OnEvent(f func(Event))
+	Watch(ctx context.Context) <-chan Event
+} = newEventHub()
+
+// eventHub delivers events to handlers and watchers.
+type eventHub struct {
+	lk       sync.RWMutex
+	handlers []func(Event)
+	watchers map[chan Event]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{
+		watchers: make(map[chan Event]struct{}),
+	}
+}
+
+// OnEvent registers f, which is called for every event.
+// f is called on the goroutine which detected the event, so it must not block.
+func (h *eventHub) OnEvent(f func(Event)) {
+	h.lk.Lock()
+	defer h.lk.Unlock()
+
+	h.handlers = append(h.handlers, f)
+}
+
+// Watch returns a channel receiving events until ctx is done.
+// Events are dropped while the channel buffer is full.
+func (h *eventHub) Watch(ctx context.Context) <-chan Event {
+	ch := make(chan Event, DefaultEventBufferSize)
+
+	h.lk.Lock()
+	h.watchers[ch] = struct{}{}
+	h.lk.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		h.lk.Lock()
+		defer h.lk.Unlock()
+
+		delete(h.watchers, ch)
+		close(ch)
+	}()
+
+	return ch
+}
+
+func (h *eventHub) emit(events ...Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	// handlers are called without the lock, so that they can call OnEvent or Watch
+	h.lk.RLock()
+	handlers := make([]func(Event), len(h.handlers))
+	copy(handlers, h.handlers)
+	h.lk.RUnlock()
+
+	for _, e := range events {
+		for _, f := range handlers {
+			f(e)
+		}
+
+		// the lock keeps Watch from closing a channel while sending to it
+		h.lk.RLock()
+		for ch := range h.watchers {
+			select {
+			case ch <- e:
+			default:
+			}
+		}
+		h.lk.RUnlock()
+	}
+}
@@ -0,0 +1,200 @@
+package mydb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestEventHub(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		h := newEventHub()
+
+		var handled []EventType
+		h.OnEvent(func(e Event) {
+			handled = append(handled, e.Type)
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := h.Watch(ctx)
+
+		h.emit(Event{Type: NodeDown}, Event{Type: NodeUp})
+
+		if len(handled) != 2 || handled[0] != NodeDown || handled[1] != NodeUp {
+			t.Errorf("OnEvent() want %v, but get %v", []EventType{NodeDown, NodeUp}, handled)
+		}
+		for _, want := range []EventType{NodeDown, NodeUp} {
+			if e := <-ch; e.Type != want {
+				t.Errorf("Watch() want %s, but get %s", want, e.Type)
+			}
+		}
+
+		cancel()
+		if _, ok := <-ch; ok {
+			t.Error("Watch() want closed channel")
+		}
+	})
+
+	t.Run("success with full buffer", func(t *testing.T) {
+		h := newEventHub()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ch := h.Watch(ctx)
+
+		for i := 0; i < DefaultEventBufferSize+1; i++ {
+			h.emit(Event{Type: LagExceeded})
+		}
+		if len(ch) != DefaultEventBufferSize {
+			t.Errorf("buffered events want %d, but get %d", DefaultEventBufferSize, len(ch))
+		}
+	})
+}
+
+func TestNodeObserveProbe(t *testing.T) {
+	t.Run("success with lag", func(t *testing.T) {
+		n := newNode(ReadReplica, "readreplica0", nil)
+
+		if events := n.observeProbe(probeResult{lag: 3 * time.Second, hasLag: true}, 1, 1, 5*time.Second); len(events) != 0 {
+			t.Errorf("events want none, but get %+v", events)
+		}
+		events := n.observeProbe(probeResult{lag: 10 * time.Second, hasLag: true}, 1, 1, 5*time.Second)
+		if len(events) != 1 || events[0].Type != LagExceeded || events[0].Lag != 10*time.Second || events[0].Node != "readreplica0" {
+			t.Errorf("unexpected events %+v", events)
+		}
+		// emitted only when the lag starts exceeding
+		if events := n.observeProbe(probeResult{lag: 11 * time.Second, hasLag: true}, 1, 1, 5*time.Second); len(events) != 0 {
+			t.Errorf("events want none, but get %+v", events)
+		}
+	})
+
+	t.Run("success with identity", func(t *testing.T) {
+		n := newNode(Master, "master", nil)
+
+		if events := n.observeProbe(probeResult{identity: "a"}, 1, 1, 0); len(events) != 0 {
+			t.Errorf("events want none, but get %+v", events)
+		}
+		events := n.observeProbe(probeResult{identity: "b"}, 1, 1, 0)
+		if len(events) != 1 || events[0].Type != MasterChanged || events[0].Identity != "b" || events[0].PreviousIdentity != "a" {
+			t.Errorf("unexpected events %+v", events)
+		}
+	})
+}
+
+func TestEvents(t *testing.T) {
+	t.Run("success with node down and up", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		db := New(master, readreplica)
+		defer db.Close()
+
+		var events []Event
+		db.OnEvent(func(e Event) {
+			events = append(events, e)
+		})
+
+		checkErr := errors.New("check error")
+		db.SetReplicaHealthChecker(HealthCheckerFunc(func(ctx context.Context, db *sql.DB) error {
+			return checkErr
+		}))
+		db.readDbBalancer.healthCheck()
+		db.SetReplicaHealthChecker(PingChecker())
+		db.readDbBalancer.healthCheck()
+
+		want := []EventType{NodeDown, FallbackActivated, NodeUp, FallbackCleared}
+		if len(events) != len(want) {
+			t.Fatalf("events want %v, but get %+v", want, events)
+		}
+		for i := range want {
+			if events[i].Type != want[i] {
+				t.Errorf("events[%d] want %s, but get %s", i, want[i], events[i].Type)
+			}
+		}
+		if events[0].Node != "readreplica0" || events[0].Err != checkErr {
+			t.Errorf("unexpected NodeDown %+v", events[0])
+		}
+	})
+
+	t.Run("success with handler calling db", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		db := New(master, readreplica)
+		defer db.Close()
+
+		db.OnEvent(func(e Event) {
+			if e.Type == FallbackActivated {
+				db.SetCircuitBreaker(CircuitBreaker{FailureThreshold: 1})
+				db.OnEvent(func(e Event) {})
+			}
+		})
+		db.SetReplicaHealthChecker(HealthCheckerFunc(func(ctx context.Context, db *sql.DB) error {
+			return errors.New("check error")
+		}))
+
+		done := make(chan struct{})
+		go func() {
+			db.readDbBalancer.healthCheck()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("healthCheck() deadlocks with a handler calling db")
+		}
+		if db.GetCircuitBreaker().FailureThreshold != 1 {
+			t.Error("GetCircuitBreaker() want FailureThreshold 1")
+		}
+	})
+
+	t.Run("success with master changed", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		masterMock.ExpectQuery("SELECT @@server_uuid").
+			WillReturnRows(sqlmock.NewRows([]string{"@@server_uuid"}).AddRow("a"))
+		masterMock.ExpectQuery("SELECT @@server_uuid").
+			WillReturnRows(sqlmock.NewRows([]string{"@@server_uuid"}).AddRow("a"))
+		masterMock.ExpectQuery("SELECT @@server_uuid").
+			WillReturnRows(sqlmock.NewRows([]string{"@@server_uuid"}).AddRow("b"))
+		masterMock.ExpectQuery("SELECT @@server_uuid").
+			WillReturnRows(sqlmock.NewRows([]string{"@@server_uuid"}).AddRow("b"))
+
+		db := New(master)
+		defer db.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ch := db.Watch(ctx)
+
+		db.SetMasterHealthChecker(ServerUUIDChecker())
+		db.masterHealthCheck()
+		db.masterHealthCheck()
+
+		e := <-ch
+		if e.Type != MasterChanged || e.Node != "master" || e.Identity != "b" || e.PreviousIdentity != "a" {
+			t.Errorf("unexpected event %+v", e)
+		}
+
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
@@ -0,0 +1,304 @@
+package mydb
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// HealthChecker probes a db. A non-nil error marks the db as unhealthy.
+type HealthChecker interface {
+	Check(ctx context.Context, db *sql.DB) error
+}
+
+// HealthCheckerFunc adapts a function to HealthChecker.
+type HealthCheckerFunc func(ctx context.Context, db *sql.DB) error
+
+func (f HealthCheckerFunc) Check(ctx context.Context, db *sql.DB) error {
+	return f(ctx, db)
+}
+
+// PingChecker checks the db by Ping. It is the default checker.
+func PingChecker() HealthChecker {
+	return HealthCheckerFunc(func(ctx context.Context, db *sql.DB) error {
+		return db.PingContext(ctx)
+	})
+}
+
+// QueryChecker checks the db by running query, which must return at least one row.
+// e.g. QueryChecker("SELECT 1 FROM code LIMIT 1") fails if the schema is missing.
+func QueryChecker(query string) HealthChecker {
+	return HealthCheckerFunc(func(ctx context.Context, db *sql.DB) error {
+		rows, err := db.QueryContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return sql.ErrNoRows
+		}
+		return rows.Err()
+	})
+}
+
+// LagChecker measures the replication lag of a db.
+// A HealthChecker which also implements LagChecker reports the lag in Status().
+type LagChecker interface {
+	Lag(ctx context.Context, db *sql.DB) (time.Duration, error)
+}
+
+// IdentityChecker returns the identity of the server behind a db, e.g. @@server_uuid.
+// A master HealthChecker which also implements IdentityChecker emits MasterChanged
+// when the identity changes.
+type IdentityChecker interface {
+	Identity(ctx context.Context, db *sql.DB) (string, error)
+}
+
+// ServerUUIDChecker checks the db by SELECT @@server_uuid.
+// It implements IdentityChecker with the server UUID.
+func ServerUUIDChecker() HealthChecker {
+	return serverUUIDChecker{}
+}
+
+type serverUUIDChecker struct{}
+
+func (c serverUUIDChecker) Check(ctx context.Context, db *sql.DB) error {
+	_, err := c.Identity(ctx, db)
+	return err
+}
+
+func (serverUUIDChecker) Identity(ctx context.Context, db *sql.DB) (string, error) {
+	var uuid string
+	err := db.QueryRowContext(ctx, "SELECT @@server_uuid").Scan(&uuid)
+	return uuid, err
+}
+
+// ReplicationChecker checks that both the IO thread and the SQL thread of replication are running.
+// It also implements LagChecker with Seconds_Behind_Source.
+func ReplicationChecker() HealthChecker {
+	return replicationChecker{}
+}
+
+type replicationChecker struct{}
+
+func (replicationChecker) Check(ctx context.Context, db *sql.DB) error {
+	status, err := replicaStatus(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	if !isYes(status, "Replica_IO_Running", "Slave_IO_Running") ||
+		!isYes(status, "Replica_SQL_Running", "Slave_SQL_Running") {
+		return ErrReplicationStopped
+	}
+	return nil
+}
+
+func (replicationChecker) Lag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	status, err := replicaStatus(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	// NULL while the SQL thread is stopped
+	v, ok := status["Seconds_Behind_Source"]
+	if !ok {
+		v = status["Seconds_Behind_Master"]
+	}
+	seconds, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, ErrReplicationStopped
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// ReadOnlyChecker checks that @@global.read_only equals readOnly.
+// Use ReadOnlyChecker(false) for master and ReadOnlyChecker(true) for readreplicas.
+func ReadOnlyChecker(readOnly bool) HealthChecker {
+	return HealthCheckerFunc(func(ctx context.Context, db *sql.DB) error {
+		var v bool
+		if err := db.QueryRowContext(ctx, "SELECT @@global.read_only").Scan(&v); err != nil {
+			return err
+		}
+
+		if v != readOnly {
+			return ErrUnexpectedReadOnly
+		}
+		return nil
+	})
+}
+
+// AllCheckers composes checkers. The db is healthy only if every checker passes,
+// and checkers run in order until the first failure.
+// The lag and the identity are measured by the first checker implementing
+// LagChecker and IdentityChecker respectively.
+func AllCheckers(checkers ...HealthChecker) HealthChecker {
+	return allCheckers(checkers)
+}
+
+type allCheckers []HealthChecker
+
+func (a allCheckers) Check(ctx context.Context, db *sql.DB) error {
+	for i := range a {
+		if err := a[i].Check(ctx, db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findChecker returns the first checker in hc, including composed ones, which matches.
+func findChecker(hc HealthChecker, match func(hc HealthChecker) bool) (HealthChecker, bool) {
+	if a, ok := hc.(allCheckers); ok {
+		for i := range a {
+			if found, ok := findChecker(a[i], match); ok {
+				return found, true
+			}
+		}
+		return nil, false
+	}
+
+	return hc, match(hc)
+}
+
+// lagCheckerOf returns the LagChecker of hc, if any.
+func lagCheckerOf(hc HealthChecker) (LagChecker, bool) {
+	found, ok := findChecker(hc, func(hc HealthChecker) bool {
+		_, ok := hc.(LagChecker)
+		return ok
+	})
+	if !ok {
+		return nil, false
+	}
+	return found.(LagChecker), true
+}
+
+// identityCheckerOf returns the IdentityChecker of hc, if any.
+func identityCheckerOf(hc HealthChecker) (IdentityChecker, bool) {
+	found, ok := findChecker(hc, func(hc HealthChecker) bool {
+		_, ok := hc.(IdentityChecker)
+		return ok
+	})
+	if !ok {
+		return nil, false
+	}
+	return found.(IdentityChecker), true
+}
+
+// checkWithTimeout runs hc with a deadline. A check which does not return
+// within timeout, e.g. on a blackholed host, fails with context.DeadlineExceeded.
+func checkWithTimeout(ctx context.Context, hc HealthChecker, db *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() { result <- hc.Check(ctx, db) }()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// probeResult is the result of a health check.
+type probeResult struct {
+	at       time.Time
+	latency  time.Duration
+	err      error
+	lag      time.Duration
+	hasLag   bool
+	identity string
+}
+
+// probe runs hc with a deadline, then measures the lag and the identity
+// if hc implements LagChecker and IdentityChecker.
+// A failed measurement leaves the value unknown but does not fail the probe.
+func probe(ctx context.Context, hc HealthChecker, db *sql.DB, timeout time.Duration) probeResult {
+	r := probeResult{at: time.Now()}
+	r.err = checkWithTimeout(ctx, hc, db, timeout)
+	r.latency = time.Since(r.at)
+	if r.err != nil {
+		return r
+	}
+
+	if lc, ok := lagCheckerOf(hc); ok {
+		var lag time.Duration
+		err := checkWithTimeout(ctx, HealthCheckerFunc(func(ctx context.Context, db *sql.DB) (err error) {
+			lag, err = lc.Lag(ctx, db)
+			return err
+		}), db, timeout)
+		if err == nil {
+			r.lag, r.hasLag = lag, true
+		}
+	}
+
+	if ic, ok := identityCheckerOf(hc); ok {
+		var identity string
+		err := checkWithTimeout(ctx, HealthCheckerFunc(func(ctx context.Context, db *sql.DB) (err error) {
+			identity, err = ic.Identity(ctx, db)
+			return err
+		}), db, timeout)
+		if err == nil {
+			r.identity = identity
+		}
+	}
+
+	return r
+}
+
+// replicaStatus returns SHOW REPLICA STATUS as a column name to value map.
+// SHOW SLAVE STATUS is used for MySQL before 8.0.22.
+func replicaStatus(ctx context.Context, db *sql.DB) (map[string]string, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS")
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotReplica
+	}
+
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	status := make(map[string]string, len(columns))
+	for i := range columns {
+		status[columns[i]] = string(values[i])
+	}
+
+	return status, rows.Err()
+}
+
+func isYes(status map[string]string, keys ...string) bool {
+	for i := range keys {
+		if v, ok := status[keys[i]]; ok {
+			return v == "Yes"
+		}
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+package mydb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPingChecker(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		if err != nil {
+			t.Error(err.Error())
+		}
+		mock.ExpectPing()
+
+		if err := PingChecker().Check(context.Background(), db); err != nil {
+			t.Error(err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
+
+func TestQueryChecker(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		mock.ExpectQuery("SELECT 1 FROM code").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+
+		if err := QueryChecker("SELECT 1 FROM code").Check(context.Background(), db); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("error with no rows", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		mock.ExpectQuery("SELECT 1 FROM code").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}))
+
+		if err := QueryChecker("SELECT 1 FROM code").Check(context.Background(), db); err != sql.ErrNoRows {
+			t.Errorf("Check() want %s, but get %v", sql.ErrNoRows, err)
+		}
+	})
+
+	t.Run("error with query", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		mock.ExpectQuery("SELECT 1 FROM code").
+			WillReturnError(errors.New("table doesn't exist"))
+
+		if err := QueryChecker("SELECT 1 FROM code").Check(context.Background(), db); err == nil {
+			t.Error("Check() want error")
+		}
+	})
+}
+
+func TestReplicationChecker(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		mock.ExpectQuery("SHOW REPLICA STATUS").
+			WillReturnRows(sqlmock.NewRows([]string{"Replica_IO_Running", "Replica_SQL_Running"}).AddRow("Yes", "Yes"))
+
+		if err := ReplicationChecker().Check(context.Background(), db); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("success with SHOW SLAVE STATUS", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		mock.ExpectQuery("SHOW REPLICA STATUS").
+			WillReturnError(errors.New("syntax error"))
+		mock.ExpectQuery("SHOW SLAVE STATUS").
+			WillReturnRows(sqlmock.NewRows([]string{"Slave_IO_Running", "Slave_SQL_Running"}).AddRow("Yes", "Yes"))
+
+		if err := ReplicationChecker().Check(context.Background(), db); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("error with stopped sql thread", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		mock.ExpectQuery("SHOW REPLICA STATUS").
+			WillReturnRows(sqlmock.NewRows([]string{"Replica_IO_Running", "Replica_SQL_Running"}).AddRow("Yes", "No"))
+
+		if err := ReplicationChecker().Check(context.Background(), db); err != ErrReplicationStopped {
+			t.Errorf("Check() want %s, but get %v", ErrReplicationStopped, err)
+		}
+	})
+
+	t.Run("error with not replica", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		mock.ExpectQuery("SHOW REPLICA STATUS").
+			WillReturnRows(sqlmock.NewRows([]string{"Replica_IO_Running", "Replica_SQL_Running"}))
+
+		if err := ReplicationChecker().Check(context.Background(), db); err != ErrNotReplica {
+			t.Errorf("Check() want %s, but get %v", ErrNotReplica, err)
+		}
+	})
+}
+
+func TestReplicationCheckerLag(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		mock.ExpectQuery("SHOW REPLICA STATUS").
+			WillReturnRows(sqlmock.NewRows([]string{"Seconds_Behind_Source"}).AddRow("5"))
+
+		lc, ok := lagCheckerOf(AllCheckers(PingChecker(), ReplicationChecker()))
+		if !ok {
+			t.Fatal("lagCheckerOf() want LagChecker")
+		}
+		lag, err := lc.Lag(context.Background(), db)
+		if err != nil {
+			t.Error(err)
+		}
+		if lag != 5*time.Second {
+			t.Errorf("Lag() want %s, but get %s", 5*time.Second, lag)
+		}
+	})
+
+	t.Run("error with stopped sql thread", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		mock.ExpectQuery("SHOW REPLICA STATUS").
+			WillReturnRows(sqlmock.NewRows([]string{"Seconds_Behind_Source"}).AddRow(nil))
+
+		if _, err := ReplicationChecker().(LagChecker).Lag(context.Background(), db); err != ErrReplicationStopped {
+			t.Errorf("Lag() want %s, but get %v", ErrReplicationStopped, err)
+		}
+	})
+
+	t.Run("success without LagChecker", func(t *testing.T) {
+		if _, ok := lagCheckerOf(AllCheckers(PingChecker())); ok {
+			t.Error("lagCheckerOf() want false")
+		}
+	})
+}
+
+func TestReadOnlyChecker(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		mock.ExpectQuery("SELECT @@global.read_only").
+			WillReturnRows(sqlmock.NewRows([]string{"@@global.read_only"}).AddRow(1))
+
+		if err := ReadOnlyChecker(true).Check(context.Background(), db); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("error with writable replica", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		mock.ExpectQuery("SELECT @@global.read_only").
+			WillReturnRows(sqlmock.NewRows([]string{"@@global.read_only"}).AddRow(0))
+
+		if err := ReadOnlyChecker(true).Check(context.Background(), db); err != ErrUnexpectedReadOnly {
+			t.Errorf("Check() want %s, but get %v", ErrUnexpectedReadOnly, err)
+		}
+	})
+}
+
+func TestAllCheckers(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var called int
+		ok := HealthCheckerFunc(func(ctx context.Context, db *sql.DB) error {
+			called++
+			return nil
+		})
+
+		if err := AllCheckers(ok, ok).Check(context.Background(), nil); err != nil {
+			t.Error(err)
+		}
+		if called != 2 {
+			t.Errorf("checkers want called %d times, but %d", 2, called)
+		}
+	})
+
+	t.Run("error with first failure", func(t *testing.T) {
+		checkErr := errors.New("check error")
+		var called int
+		ng := HealthCheckerFunc(func(ctx context.Context, db *sql.DB) error {
+			called++
+			return checkErr
+		})
+
+		if err := AllCheckers(ng, ng).Check(context.Background(), nil); err != checkErr {
+			t.Errorf("Check() want %s, but get %v", checkErr, err)
+		}
+		if called != 1 {
+			t.Errorf("checkers want called %d times, but %d", 1, called)
+		}
+	})
+}
+
+func TestCheckWithTimeout(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ok := HealthCheckerFunc(func(ctx context.Context, db *sql.DB) error {
+			return nil
+		})
+
+		if err := checkWithTimeout(context.Background(), ok, nil, time.Second); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("error with timeout", func(t *testing.T) {
+		block := make(chan struct{})
+		defer close(block)
+		blackhole := HealthCheckerFunc(func(ctx context.Context, db *sql.DB) error {
+			<-block
+			return nil
+		})
+
+		start := time.Now()
+		err := checkWithTimeout(context.Background(), blackhole, nil, 10*time.Millisecond)
+		if err != context.DeadlineExceeded {
+			t.Errorf("checkWithTimeout() want %s, but get %v", context.DeadlineExceeded, err)
+		}
+		if time.Since(start) > time.Second {
+			t.Error("checkWithTimeout() want to return at deadline")
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package mydb
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// latencyWindowSize is the number of recent read latencies kept for Hedging.Quantile.
+	latencyWindowSize = 1024
+	// DefaultHedgingMinSamples is the number of samples needed before Hedging.Quantile is used.
+	DefaultHedgingMinSamples = 100
+)
+
+// Hedging configures hedged reads. If the first readreplica has not responded
+// within the hedging delay, the same read is sent to a second readreplica.
+// The first response wins and the other read is cancelled through its context.
+// The zero value disables hedging.
+type Hedging struct {
+	// Delay is the fixed hedging delay.
+	Delay time.Duration
+	// Quantile, e.g. 0.95, uses the quantile of recent read latencies as the hedging delay.
+	// Delay is used until DefaultHedgingMinSamples reads are observed.
+	Quantile float64
+}
+
+func (h Hedging) enabled() bool {
+	return h.Delay > 0 || h.Quantile > 0
+}
+
+type readFunc func(ctx context.Context, d *sql.DB) (interface{}, error)
+
+type readResult struct {
+	res    interface{}
+	err    error
+	d      *sql.DB
+	cancel context.CancelFunc
+}
+
+// hedge runs fn on d, and on a second readreplica if d is slower than the hedging delay.
+// It returns the first successful result and the db which served it.
+//
+// The context of the winner is not cancelled here, because cancelling it would close
+// the returned rows. It is released when ctx is done.
+func (db *DB) hedge(ctx context.Context, d *sql.DB, fn readFunc) (interface{}, *sql.DB, error) {
+	h := db.GetHedging()
+	start := time.Now()
+	delay := db.hedgeDelay(h)
+	if delay <= 0 || d == db.master {
+		done := db.track(d, opQuery)
+		res, err := fn(ctx, d)
+		done(err)
+		db.observeReadLatency(h, start)
+		return res, d, err
+	}
+
+	results := make(chan readResult, 2)
+	launch := func(d *sql.DB) {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		go func() {
+			done := db.track(d, opQuery)
+			res, err := fn(attemptCtx, d)
+			done(err)
+			results <- readResult{res: res, err: err, d: d, cancel: cancel}
+		}()
+	}
+
+	launch(d)
+	attempts := 1
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case r := <-results:
+		db.observeReadLatency(h, start)
+		r.release()
+		return r.res, r.d, r.err
+	case <-timer.C:
+		if second := db.readDbBalancer.GetExcluding([]*sql.DB{d}); second != nil && db.allow(second) {
+			launch(second)
+			attempts++
+			setSpanAttribute(ctx, "mydb.hedged", true)
+		}
+	}
+
+	r := <-results
+	if r.err != nil && attempts == 2 {
+		// the other read may still succeed
+		r.cancel()
+		r = <-results
+	} else if attempts == 2 {
+		go func() {
+			loser := <-results
+			loser.cancel()
+		}()
+	}
+
+	db.observeReadLatency(h, start)
+	r.release()
+	return r.res, r.d, r.err
+}
+
+// release cancels the context of a failed attempt, which has no result using it.
+func (r readResult) release() {
+	if r.err != nil {
+		r.cancel()
+	}
+}
+
+func (db *DB) hedgeDelay(h Hedging) time.Duration {
+	if !h.enabled() {
+		return 0
+	}
+
+	if h.Quantile > 0 {
+		if q, ok := db.readLatency.Quantile(h.Quantile, DefaultHedgingMinSamples); ok {
+			return q
+		}
+	}
+	return h.Delay
+}
+
+func (db *DB) observeReadLatency(h Hedging, start time.Time) {
+	if h.Quantile > 0 {
+		db.readLatency.Observe(time.Since(start))
+	}
+}
+
+var _ interface {
+	Observe(d time.Duration)
+	Quantile(q float64, minSamples int) (time.Duration, bool)
+} = newLatencyWindow()
+
+// latencyWindow keeps recent latencies in a ring buffer.
+type latencyWindow struct {
+	lk      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func newLatencyWindow() *latencyWindow {
+	return &latencyWindow{
+		samples: make([]time.Duration, 0, latencyWindowSize),
+	}
+}
+
+func (w *latencyWindow) Observe(d time.Duration) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	if len(w.samples) < latencyWindowSize {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % latencyWindowSize
+}
+
+// Quantile returns the q quantile of recent latencies.
+// It returns false if there are less than minSamples samples.
+func (w *latencyWindow) Quantile(q float64, minSamples int) (time.Duration, bool) {
+	w.lk.Lock()
+	if len(w.samples) == 0 || len(w.samples) < minSamples {
+		w.lk.Unlock()
+		return 0, false
+	}
+	samples := make([]time.Duration, len(w.samples))
+	copy(samples, w.samples)
+	w.lk.Unlock()
+
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i] < samples[j]
+	})
+
+	i := int(q * float64(len(samples)))
+	if i >= len(samples) {
+		i = len(samples) - 1
+	}
+	return samples[i], true
+}
@@ -0,0 +1,171 @@
+package mydb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLatencyWindow(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		w := newLatencyWindow()
+		if _, ok := w.Quantile(0.95, 1); ok {
+			t.Error("Quantile() want false without samples")
+		}
+
+		for i := 1; i <= 100; i++ {
+			w.Observe(time.Duration(i) * time.Millisecond)
+		}
+		if _, ok := w.Quantile(0.95, 101); ok {
+			t.Error("Quantile() want false under min samples")
+		}
+
+		q, ok := w.Quantile(0.95, 100)
+		if !ok || q != 96*time.Millisecond {
+			t.Errorf("Quantile() want %s, but get %s", 96*time.Millisecond, q)
+		}
+	})
+
+	t.Run("success with ring buffer", func(t *testing.T) {
+		w := newLatencyWindow()
+		for i := 0; i < latencyWindowSize; i++ {
+			w.Observe(time.Second)
+		}
+		for i := 0; i < latencyWindowSize; i++ {
+			w.Observe(time.Millisecond)
+		}
+
+		q, _ := w.Quantile(0.99, 1)
+		if q != time.Millisecond {
+			t.Errorf("Quantile() want %s, but get %s", time.Millisecond, q)
+		}
+	})
+}
+
+func TestHedging(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica0, readreplica0Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1, readreplica1Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		// readreplica1 stalls
+		readreplica1Mock.ExpectQuery("select 1").
+			WillDelayFor(time.Minute).
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+		readreplica0Mock.ExpectQuery("select 1").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+
+		db := New(master, readreplica0, readreplica1)
+		defer db.Close()
+		db.SetBalanceAlgorithm(RoundRobin)
+		db.SetHedging(Hedging{Delay: 10 * time.Millisecond})
+		if db.GetHedging().Delay != 10*time.Millisecond {
+			t.Error("GetHedging() want Delay 10ms")
+		}
+
+		start := time.Now()
+		rows, err := db.QueryContext(context.Background(), "select 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		if time.Since(start) > 10*time.Second {
+			t.Error("QueryContext() want hedged read")
+		}
+
+		if err := readreplica0Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("success with winner context", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, readreplicaMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplicaMock.ExpectQuery("select 1").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+		readreplicaMock.ExpectQuery("select 1").
+			WillReturnError(errors.New("Error 1146: Table 'code' doesn't exist"))
+
+		db := New(master, readreplica)
+		defer db.Close()
+		db.SetHedging(Hedging{Delay: time.Minute})
+
+		var attemptCtx context.Context
+		fn := func(ctx context.Context, d *sql.DB) (interface{}, error) {
+			attemptCtx = ctx
+			return d.QueryContext(ctx, "select 1")
+		}
+
+		res, _, err := db.hedge(context.Background(), readreplica, fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.(*sql.Rows).Close()
+		if attemptCtx.Err() != nil {
+			t.Error("attempt context want alive while rows are open")
+		}
+
+		if _, _, err = db.hedge(context.Background(), readreplica, fn); err == nil {
+			t.Fatal("hedge() want error")
+		}
+		if attemptCtx.Err() == nil {
+			t.Error("attempt context want cancelled after error")
+		}
+
+		if err := readreplicaMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("success without hedging", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica0, readreplica0Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1, readreplica1Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1Mock.ExpectQuery("select 1").
+			WillDelayFor(50 * time.Millisecond).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		db := New(master, readreplica0, readreplica1)
+		defer db.Close()
+		db.SetBalanceAlgorithm(RoundRobin)
+
+		var id int
+		if err = db.QueryRowContext(context.Background(), "select 1").Scan(&id); err != nil {
+			t.Error(err)
+		}
+
+		if err := readreplica0Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+		if err := readreplica1Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package mydb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Call is a routed call of Query, QueryRow, Exec, Prepare or BeginTx.
+// Interceptors may rewrite Query, Args and TxOptions before calling the next handler.
+type Call struct {
+	// Method is "Query", "QueryRow", "Exec", "Prepare" or "BeginTx".
+	Method    string
+	Query     string
+	Args      []interface{}
+	TxOptions *sql.TxOptions
+
+	// Node and Role are the node which served the call.
+	// They are set when the next handler returns, and Node is empty if no node was selected.
+	Node string
+	Role Role
+}
+
+func (c *Call) route(n *node) {
+	if n == nil {
+		return
+	}
+	c.Node = n.Name()
+	c.Role = n.Role()
+}
+
+// Handler runs a call. The result is *sql.Rows for Query, *sql.Row for QueryRow,
+// sql.Result for Exec, *sql.Stmt for Prepare and *sql.Tx for BeginTx.
+type Handler func(ctx context.Context, call *Call) (interface{}, error)
+
+// Interceptor wraps a handler, e.g. for auditing, query rewriting or guards.
+// It must return a result of the same type as next, or an error.
+type Interceptor func(next Handler) Handler
+
+// Use adds interceptors to every routed call.
+// The interceptor added first is the outermost.
+func (db *DB) Use(interceptors ...Interceptor) {
+	db.lk.Lock()
+	defer db.lk.Unlock()
+
+	db.interceptors = append(db.interceptors, interceptors...)
+}
+
+// intercept runs call on h wrapped by the interceptors, and logs it if it is slow.
+func (db *DB) intercept(ctx context.Context, call *Call, h Handler) (interface{}, error) {
+	db.lk.RLock()
+	interceptors := db.interceptors
+	db.lk.RUnlock()
+
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		h = interceptors[i](h)
+	}
+
+	start := time.Now()
+	res, err := h(ctx, call)
+	db.logSlowQuery(call, time.Since(start))
+	return res, err
+}
@@ -0,0 +1,206 @@
+package mydb
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUse(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, readreplicaMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplicaMock.ExpectQuery("SELECT \\* FROM code /\\* app \\*/").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		masterMock.ExpectExec("INSERT INTO code /\\* app \\*/").
+			WithArgs(2).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+
+		db := New(master, readreplica)
+		defer db.Close()
+
+		var order []string
+		var calls []Call
+		db.Use(
+			func(next Handler) Handler {
+				return func(ctx context.Context, call *Call) (interface{}, error) {
+					order = append(order, "audit")
+					res, err := next(ctx, call)
+					calls = append(calls, *call)
+					return res, err
+				}
+			},
+			func(next Handler) Handler {
+				return func(ctx context.Context, call *Call) (interface{}, error) {
+					order = append(order, "rewrite")
+					call.Query += " /* app */"
+					return next(ctx, call)
+				}
+			},
+		)
+
+		rows, err := db.Query("SELECT * FROM code", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+		if _, err := db.Exec("INSERT INTO code", 2); err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Join(order, ",") != "audit,rewrite,audit,rewrite" {
+			t.Errorf("interceptors order want %s, but get %v", "audit,rewrite,audit,rewrite", order)
+		}
+		if len(calls) != 2 {
+			t.Fatalf("calls want %d, but get %d", 2, len(calls))
+		}
+		if calls[0].Method != "Query" || calls[0].Node != "readreplica0" || calls[0].Role != ReadReplica || calls[0].Args[0] != 1 {
+			t.Errorf("unexpected call %+v", calls[0])
+		}
+		if calls[1].Method != "Exec" || calls[1].Node != "master" || calls[1].Role != Master {
+			t.Errorf("unexpected call %+v", calls[1])
+		}
+
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+		if err := readreplicaMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("error with guard", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		db := New(master)
+		defer db.Close()
+
+		guardErr := errors.New("DELETE without WHERE")
+		db.Use(func(next Handler) Handler {
+			return func(ctx context.Context, call *Call) (interface{}, error) {
+				if strings.HasPrefix(call.Query, "DELETE") && !strings.Contains(call.Query, "WHERE") {
+					return nil, guardErr
+				}
+				return next(ctx, call)
+			}
+		})
+
+		if _, err := db.Exec("DELETE FROM code"); err != guardErr {
+			t.Errorf("Exec() want %s, but get %v", guardErr, err)
+		}
+		var id int
+		if err := db.QueryRow("DELETE FROM code").Scan(&id); err != guardErr {
+			t.Errorf("QueryRow().Scan() want %s, but get %v", guardErr, err)
+		}
+
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("success with BeginTx and Prepare", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		masterMock.ExpectBegin()
+		masterMock.ExpectPrepare("SELECT 1")
+
+		db := New(master)
+		defer db.Close()
+
+		var methods []string
+		db.Use(func(next Handler) Handler {
+			return func(ctx context.Context, call *Call) (interface{}, error) {
+				res, err := next(ctx, call)
+				methods = append(methods, call.Method+"@"+call.Node)
+				return res, err
+			}
+		})
+
+		if _, err := db.Begin(); err != nil {
+			t.Error(err)
+		}
+		if _, err := db.Prepare("SELECT 1"); err != nil {
+			t.Error(err)
+		}
+
+		if strings.Join(methods, ",") != "BeginTx@master,Prepare@master" {
+			t.Errorf("methods want %s, but get %v", "BeginTx@master,Prepare@master", methods)
+		}
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+	t.Run("error with nil result", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		db := New(master)
+		defer db.Close()
+
+		db.Use(func(next Handler) Handler {
+			return func(ctx context.Context, call *Call) (interface{}, error) {
+				return nil, nil
+			}
+		})
+
+		if rows, err := db.Query("SELECT 1"); rows != nil || err != ErrNilResult {
+			t.Errorf("Query() want %s, but get %v", ErrNilResult, err)
+		}
+		if tx, err := db.Begin(); tx != nil || err != ErrNilResult {
+			t.Errorf("Begin() want %s, but get %v", ErrNilResult, err)
+		}
+		if tx, err := db.BeginTracedTx(context.Background(), nil); tx != nil || err != ErrNilResult {
+			t.Errorf("BeginTracedTx() want %s, but get %v", ErrNilResult, err)
+		}
+		if result, err := db.Exec("DELETE FROM code WHERE id = 1"); result != nil || err != ErrNilResult {
+			t.Errorf("Exec() want %s, but get %v", ErrNilResult, err)
+		}
+		if stmt, err := db.Prepare("SELECT 1"); stmt != nil || err != ErrNilResult {
+			t.Errorf("Prepare() want %s, but get %v", ErrNilResult, err)
+		}
+
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("error with wrong result type", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		db := New(master)
+		defer db.Close()
+
+		db.Use(func(next Handler) Handler {
+			return func(ctx context.Context, call *Call) (interface{}, error) {
+				return "cached", nil
+			}
+		})
+
+		if _, err := db.Query("SELECT 1"); err != ErrNilResult {
+			t.Errorf("Query() want %s, but get %v", ErrNilResult, err)
+		}
+		if _, err := db.Exec("DELETE FROM code WHERE id = 1"); err != ErrNilResult {
+			t.Errorf("Exec() want %s, but get %v", ErrNilResult, err)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package mydb
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Logger logs messages with alternating keys and values.
+// *slog.Logger satisfies it as is.
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// NopLogger discards all messages. It is the default logger.
+func NopLogger() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, keyvals ...interface{}) {}
+func (nopLogger) Info(msg string, keyvals ...interface{})  {}
+func (nopLogger) Warn(msg string, keyvals ...interface{})  {}
+func (nopLogger) Error(msg string, keyvals ...interface{}) {}
+
+// StdLogger adapts l to Logger. Messages are written as
+// "level=WARN msg=\"health check failed\" node=readreplica0 err=...".
+func StdLogger(l *log.Logger) Logger {
+	return stdLogger{l: l}
+}
+
+type stdLogger struct {
+	l *log.Logger
+}
+
+func (s stdLogger) Debug(msg string, keyvals ...interface{}) { s.log("DEBUG", msg, keyvals) }
+func (s stdLogger) Info(msg string, keyvals ...interface{})  { s.log("INFO", msg, keyvals) }
+func (s stdLogger) Warn(msg string, keyvals ...interface{})  { s.log("WARN", msg, keyvals) }
+func (s stdLogger) Error(msg string, keyvals ...interface{}) { s.log("ERROR", msg, keyvals) }
+
+func (s stdLogger) log(level, msg string, keyvals []interface{}) {
+	var b strings.Builder
+	b.WriteString("level=" + level + " msg=" + logValue(msg))
+	for i := 0; i < len(keyvals); i += 2 {
+		b.WriteString(" " + fmt.Sprint(keyvals[i]) + "=")
+		if i+1 < len(keyvals) {
+			b.WriteString(logValue(fmt.Sprint(keyvals[i+1])))
+		} else {
+			b.WriteString("(MISSING)")
+		}
+	}
+	s.l.Output(3, b.String())
+}
+
+// logValue quotes v if it is empty or contains spaces, quotes or '='.
+func logValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// logEvent logs e at the level of its severity.
+func logEvent(l Logger, e Event) {
+	keyvals := []interface{}{"event", e.Type.String()}
+	if e.Node != "" {
+		keyvals = append(keyvals, "node", e.Node, "role", e.Role.String())
+	}
+
+	switch e.Type {
+	case NodeDown:
+		l.Error("node down", append(keyvals, "err", e.Err)...)
+	case NodeUp:
+		l.Info("node up", keyvals...)
+	case LagExceeded:
+		l.Warn("replication lag exceeded", append(keyvals, "lag", e.Lag)...)
+	case FallbackActivated:
+		l.Error("no readreplica available, reads fall back", keyvals...)
+	case FallbackCleared:
+		l.Info("readreplica available again", keyvals...)
+	case MasterChanged:
+		l.Warn("master changed", append(keyvals, "identity", e.Identity, "previous_identity", e.PreviousIdentity)...)
+	}
+}
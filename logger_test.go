@@ -0,0 +1,114 @@
+package mydb
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type testLogEntry struct {
+	level   string
+	msg     string
+	keyvals []interface{}
+}
+
+type testLogger struct {
+	lk      sync.Mutex
+	entries []testLogEntry
+}
+
+func (l *testLogger) Debug(msg string, keyvals ...interface{}) { l.log("DEBUG", msg, keyvals) }
+func (l *testLogger) Info(msg string, keyvals ...interface{})  { l.log("INFO", msg, keyvals) }
+func (l *testLogger) Warn(msg string, keyvals ...interface{})  { l.log("WARN", msg, keyvals) }
+func (l *testLogger) Error(msg string, keyvals ...interface{}) { l.log("ERROR", msg, keyvals) }
+
+func (l *testLogger) log(level, msg string, keyvals []interface{}) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	l.entries = append(l.entries, testLogEntry{level: level, msg: msg, keyvals: keyvals})
+}
+
+func (l *testLogger) find(level, msg string) (testLogEntry, bool) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	for _, e := range l.entries {
+		if e.level == level && e.msg == msg {
+			return e, true
+		}
+	}
+	return testLogEntry{}, false
+}
+
+func TestStdLogger(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := StdLogger(log.New(&buf, "", 0))
+
+		l.Warn("health check failed", "node", "readreplica0", "err", errors.New("connection refused"), "odd")
+
+		want := `level=WARN msg="health check failed" node=readreplica0 err="connection refused" odd=(MISSING)` + "\n"
+		if buf.String() != want {
+			t.Errorf("StdLogger() want %q, but get %q", want, buf.String())
+		}
+	})
+}
+
+func TestLogging(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		db := New(master, readreplica)
+		defer db.Close()
+
+		l := &testLogger{}
+		db.SetLogger(l)
+		db.SetBalanceAlgorithm(P2C)
+
+		checkErr := errors.New("check error")
+		db.SetReplicaHealthChecker(HealthCheckerFunc(func(ctx context.Context, db *sql.DB) error {
+			return checkErr
+		}))
+		db.readDbBalancer.healthCheck()
+
+		if e, ok := l.find("INFO", "config changed"); !ok || e.keyvals[1] != "BalanceAlgorithm" || e.keyvals[3] != P2C {
+			t.Errorf("config change want logged, but get %+v", l.entries)
+		}
+		if e, ok := l.find("WARN", "health check failed"); !ok || e.keyvals[1] != "readreplica0" || e.keyvals[5] != checkErr {
+			t.Errorf("health check failure want logged, but get %+v", l.entries)
+		}
+		if _, ok := l.find("ERROR", "node down"); !ok {
+			t.Errorf("node down want logged, but get %+v", l.entries)
+		}
+		if _, ok := l.find("ERROR", "no readreplica available, reads fall back"); !ok {
+			t.Errorf("fallback want logged, but get %+v", l.entries)
+		}
+	})
+
+	t.Run("success with nil logger", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		db := New(master)
+		defer db.Close()
+
+		db.SetLogger(nil)
+		db.SetMaxOpenConns(10)
+	})
+}
@@ -0,0 +1,283 @@
+package mydb
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	opQuery   = "query"
+	opExec    = "exec"
+	opBegin   = "begin"
+	opPrepare = "prepare"
+)
+
+// DefaultMetricsBuckets are the upper bounds in seconds of the call latency histogram.
+var DefaultMetricsBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type callKey struct {
+	node string
+	role string
+	op   string
+}
+
+type errorKey struct {
+	callKey
+	code string
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(buckets []float64, v float64) {
+	i := sort.SearchFloat64s(buckets, v)
+	if i < len(buckets) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+}
+
+// metrics are the counters of calls recorded for the Prometheus exporter.
+type metrics struct {
+	reads     int64
+	writes    int64
+	fallbacks int64
+	retries   int64
+
+	lk      sync.Mutex
+	buckets []float64
+	calls   map[callKey]*histogram
+	errors  map[errorKey]uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		buckets: DefaultMetricsBuckets,
+		calls:   make(map[callKey]*histogram),
+		errors:  make(map[errorKey]uint64),
+	}
+}
+
+func (m *metrics) observeCall(n *node, op string, err error, latency time.Duration) {
+	if n == nil {
+		return
+	}
+	key := callKey{node: n.Name(), role: n.Role().String(), op: op}
+
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	h, ok := m.calls[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.calls[key] = h
+	}
+	h.observe(m.buckets, latency.Seconds())
+
+	if err != nil {
+		m.errors[errorKey{callKey: key, code: errorCode(err)}]++
+	}
+}
+
+// observeRoute counts a routed read or write.
+func (m *metrics) observeRoute(read bool) {
+	if read {
+		atomic.AddInt64(&m.reads, 1)
+	} else {
+		atomic.AddInt64(&m.writes, 1)
+	}
+}
+
+func (m *metrics) observeRetry() {
+	atomic.AddInt64(&m.retries, 1)
+}
+
+func (m *metrics) observeFallback() {
+	atomic.AddInt64(&m.fallbacks, 1)
+}
+
+func (m *metrics) Reads() int64 {
+	return atomic.LoadInt64(&m.reads)
+}
+
+func (m *metrics) Writes() int64 {
+	return atomic.LoadInt64(&m.writes)
+}
+
+func (m *metrics) Fallbacks() int64 {
+	return atomic.LoadInt64(&m.fallbacks)
+}
+
+func (m *metrics) Retries() int64 {
+	return atomic.LoadInt64(&m.retries)
+}
+
+var mysqlErrorPattern = regexp.MustCompile(`^Error (\d+)`)
+
+// errorCode returns the MySQL error number of err, e.g. "1062".
+// Errors without a number are "conn", "timeout", "canceled" or "other".
+func errorCode(err error) string {
+	if m := mysqlErrorPattern.FindStringSubmatch(err.Error()); m != nil {
+		return m[1]
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case isConnError(err):
+		return "conn"
+	default:
+		return "other"
+	}
+}
+
+// MetricsHandler returns an http.Handler serving the metrics of db in Prometheus text format.
+func MetricsHandler(db *DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		db.WriteMetrics(w)
+	})
+}
+
+// WriteMetrics writes the metrics of db in Prometheus text format.
+func (db *DB) WriteMetrics(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	m := db.metrics
+
+	// copy under the lock, so that a slow writer does not block observeCall
+	m.lk.Lock()
+	calls := make(map[callKey]histogram, len(m.calls))
+	for key, h := range m.calls {
+		calls[key] = histogram{counts: append([]uint64(nil), h.counts...), count: h.count, sum: h.sum}
+	}
+	errCounts := make(map[errorKey]uint64, len(m.errors))
+	for key, v := range m.errors {
+		errCounts[key] = v
+	}
+	buckets := m.buckets
+	m.lk.Unlock()
+
+	callKeys := make([]callKey, 0, len(calls))
+	for key := range calls {
+		callKeys = append(callKeys, key)
+	}
+	sort.Slice(callKeys, func(i, j int) bool { return callKeys[i].less(callKeys[j]) })
+
+	writeHeader(bw, "mydb_call_duration_seconds", "histogram", "Latency of calls by node, role and operation.")
+	for _, key := range callKeys {
+		h := calls[key]
+		var cumulative uint64
+		for i, le := range buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(bw, "mydb_call_duration_seconds_bucket{%s,le=%q} %d\n", key.labels(), formatFloat(le), cumulative)
+		}
+		fmt.Fprintf(bw, "mydb_call_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", key.labels(), h.count)
+		fmt.Fprintf(bw, "mydb_call_duration_seconds_sum{%s} %s\n", key.labels(), formatFloat(h.sum))
+		fmt.Fprintf(bw, "mydb_call_duration_seconds_count{%s} %d\n", key.labels(), h.count)
+	}
+
+	errs := make([]errorKey, 0, len(errCounts))
+	for key := range errCounts {
+		errs = append(errs, key)
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		if errs[i].callKey != errs[j].callKey {
+			return errs[i].callKey.less(errs[j].callKey)
+		}
+		return errs[i].code < errs[j].code
+	})
+
+	writeHeader(bw, "mydb_call_errors_total", "counter", "Failed calls by node, role, operation and MySQL error code.")
+	for _, key := range errs {
+		fmt.Fprintf(bw, "mydb_call_errors_total{%s,code=\"%s\"} %d\n", key.labels(), escapeLabel(key.code), errCounts[key])
+	}
+
+	writeHeader(bw, "mydb_read_fallbacks_total", "counter", "Reads routed to master because no readreplica was available.")
+	fmt.Fprintf(bw, "mydb_read_fallbacks_total %d\n", m.Fallbacks())
+
+	writeHeader(bw, "mydb_read_retries_total", "counter", "Reads retried on another node after a connection error.")
+	fmt.Fprintf(bw, "mydb_read_retries_total %d\n", m.Retries())
+
+	writeHeader(bw, "mydb_ejections_total", "counter", "Readreplicas ejected by outlier detection.")
+	fmt.Fprintf(bw, "mydb_ejections_total %d\n", db.readDbBalancer.Ejections())
+
+	status := db.Status()
+	gauges := []struct {
+		name, typ, help string
+		value           func(n NodeStatus) (float64, bool)
+	}{
+		{"mydb_node_healthy", "gauge", "1 if the last health checks passed.", func(n NodeStatus) (float64, bool) { return boolValue(n.Healthy), true }},
+		{"mydb_node_available", "gauge", "1 if the node is used for routing.", func(n NodeStatus) (float64, bool) { return boolValue(n.Available), true }},
+		{"mydb_node_breaker_state", "gauge", "Circuit breaker state. 0 closed, 1 open, 2 half-open.", func(n NodeStatus) (float64, bool) { return float64(n.Breaker), true }},
+		{"mydb_node_inflight", "gauge", "Outstanding calls.", func(n NodeStatus) (float64, bool) { return float64(n.Inflight), true }},
+		{"mydb_replication_lag_seconds", "gauge", "Replication lag measured by the health checker.", func(n NodeStatus) (float64, bool) { return n.Lag.Seconds(), n.HasLag }},
+		{"mydb_pool_max_open_connections", "gauge", "Maximum number of open connections.", func(n NodeStatus) (float64, bool) { return float64(n.Stats.MaxOpenConnections), true }},
+		{"mydb_pool_open_connections", "gauge", "Established connections both in use and idle.", func(n NodeStatus) (float64, bool) { return float64(n.Stats.OpenConnections), true }},
+		{"mydb_pool_in_use_connections", "gauge", "Connections currently in use.", func(n NodeStatus) (float64, bool) { return float64(n.Stats.InUse), true }},
+		{"mydb_pool_idle_connections", "gauge", "Idle connections.", func(n NodeStatus) (float64, bool) { return float64(n.Stats.Idle), true }},
+		{"mydb_pool_wait_count_total", "counter", "Connections waited for.", func(n NodeStatus) (float64, bool) { return float64(n.Stats.WaitCount), true }},
+		{"mydb_pool_wait_duration_seconds_total", "counter", "Time blocked waiting for a new connection.", func(n NodeStatus) (float64, bool) { return n.Stats.WaitDuration.Seconds(), true }},
+		{"mydb_pool_max_idle_closed_total", "counter", "Connections closed due to SetMaxIdleConns.", func(n NodeStatus) (float64, bool) { return float64(n.Stats.MaxIdleClosed), true }},
+		{"mydb_pool_max_idle_time_closed_total", "counter", "Connections closed due to SetConnMaxIdleTime.", func(n NodeStatus) (float64, bool) { return float64(n.Stats.MaxIdleTimeClosed), true }},
+		{"mydb_pool_max_lifetime_closed_total", "counter", "Connections closed due to SetConnMaxLifetime.", func(n NodeStatus) (float64, bool) { return float64(n.Stats.MaxLifetimeClosed), true }},
+	}
+	for _, g := range gauges {
+		writeHeader(bw, g.name, g.typ, g.help)
+		for _, n := range status.Nodes {
+			if v, ok := g.value(n); ok {
+				fmt.Fprintf(bw, "%s{node=\"%s\",role=\"%s\"} %s\n", g.name, escapeLabel(n.Name), n.Role, formatFloat(v))
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+func (k callKey) less(o callKey) bool {
+	if k.node != o.node {
+		return k.node < o.node
+	}
+	return k.op < o.op
+}
+
+func (k callKey) labels() string {
+	return fmt.Sprintf(`node="%s",role="%s",op="%s"`, escapeLabel(k.node), escapeLabel(k.role), escapeLabel(k.op))
+}
+
+func writeHeader(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,186 @@
+package mydb
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{errors.New("Error 1062 (23000): Duplicate entry '1' for key 'PRIMARY'"), "1062"},
+		{errors.New("Error 1146: Table 'test.code' doesn't exist"), "1146"},
+		{context.DeadlineExceeded, "timeout"},
+		{context.Canceled, "canceled"},
+		{driver.ErrBadConn, "conn"},
+		{errors.New("unknown"), "other"},
+	}
+
+	for _, tt := range tests {
+		if got := errorCode(tt.err); got != tt.want {
+			t.Errorf("errorCode(%v) want %s, but get %s", tt.err, tt.want, got)
+		}
+	}
+}
+
+func TestHistogramObserve(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		buckets := []float64{0.1, 1}
+		h := &histogram{counts: make([]uint64, len(buckets))}
+		h.observe(buckets, 0.05)
+		h.observe(buckets, 0.1)
+		h.observe(buckets, 0.5)
+		h.observe(buckets, 2)
+
+		if h.counts[0] != 2 || h.counts[1] != 1 || h.count != 4 || h.sum != 2.65 {
+			t.Errorf("unexpected histogram %+v", h)
+		}
+	})
+}
+
+func TestWriteMetrics(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, readreplicaMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplicaMock.ExpectQuery("SELECT 1").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+		masterMock.ExpectExec("INSERT INTO code").
+			WillReturnError(errors.New("Error 1062 (23000): Duplicate entry '1' for key 'PRIMARY'"))
+		masterMock.ExpectQuery("SELECT 1").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+
+		db := New(master, readreplica)
+		defer db.Close()
+
+		rows, err := db.Query("SELECT 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+		if _, err := db.Exec("INSERT INTO code VALUES (1)"); err == nil {
+			t.Error("Exec() want error")
+		}
+
+		// all readreplicas died, reads fall back to master
+		db.readDbBalancer.availableDbs.Replace(nil)
+		rows, err = db.Query("SELECT 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+
+		var buf bytes.Buffer
+		if err := db.WriteMetrics(&buf); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+
+		for _, want := range []string{
+			"# TYPE mydb_call_duration_seconds histogram\n",
+			`mydb_call_duration_seconds_count{node="readreplica0",role="readreplica",op="query"} 1` + "\n",
+			`mydb_call_duration_seconds_count{node="master",role="master",op="query"} 1` + "\n",
+			`mydb_call_duration_seconds_bucket{node="master",role="master",op="exec",le="+Inf"} 1` + "\n",
+			`mydb_call_errors_total{node="master",role="master",op="exec",code="1062"} 1` + "\n",
+			"mydb_read_fallbacks_total 1\n",
+			`mydb_node_healthy{node="readreplica0",role="readreplica"} 1` + "\n",
+			`mydb_node_available{node="readreplica0",role="readreplica"} 0` + "\n",
+			`mydb_pool_open_connections{node="master",role="master"} `,
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("WriteMetrics() want to contain %q, but get\n%s", want, out)
+			}
+		}
+		if strings.Contains(out, "mydb_replication_lag_seconds{") {
+			t.Error("WriteMetrics() want no lag without LagChecker")
+		}
+
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+		if err := readreplicaMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("success with observeCall while writing", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		db := New(master)
+		defer db.Close()
+		// enough series to fill the write buffer while writing the histograms
+		for i := 0; i < 100; i++ {
+			db.metrics.observeCall(newNode(ReadReplica, fmt.Sprintf("readreplica%d", i), nil), opQuery, nil, time.Millisecond)
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- db.WriteMetrics(writerFunc(func(p []byte) (int, error) {
+				db.metrics.observeCall(db.masterNode, opExec, nil, time.Millisecond)
+				return len(p), nil
+			}))
+		}()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Error(err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("WriteMetrics() blocks observeCall")
+		}
+	})
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestMetricsHandler(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		db := New(master)
+		defer db.Close()
+		db.metrics.observeCall(db.masterNode, opExec, nil, 3*time.Millisecond)
+
+		w := httptest.NewRecorder()
+		MetricsHandler(db).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("status code want %d, but get %d", http.StatusOK, w.Code)
+		}
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+			t.Errorf("unexpected Content-Type %s", w.Header().Get("Content-Type"))
+		}
+		want := `mydb_call_duration_seconds_bucket{node="master",role="master",op="exec",le="0.005"} 1`
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("body want to contain %q, but get\n%s", want, w.Body.String())
+		}
+	})
+}
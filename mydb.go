@@ -3,6 +3,8 @@ package mydb
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"sync"
 	"time"
 )
 
@@ -13,8 +15,22 @@ const (
 	UseMaster
 )
 
+func (t FallbackType) String() string {
+	switch t {
+	case None:
+		return "none"
+	case UseMaster:
+		return "use_master"
+	default:
+		return "unknown"
+	}
+}
+
 const (
 	DefaultHealthCheckIntervalMilli = 5000
+	DefaultHealthCheckTimeoutMilli  = 1000
+	DefaultHealthCheckRise          = 1
+	DefaultHealthCheckFall          = 1
 	DefaultBalanceAlgorithm         = Random
 	DefaultFallbackType             = UseMaster
 )
@@ -23,10 +39,30 @@ type DB struct {
 	ctx            context.Context
 	cancel         context.CancelFunc
 	master         *sql.DB
+	masterNode     *node
 	masterHealth   error
 	readreplicas   []*sql.DB
 	readDbBalancer *dbBalancer
 	fallbackType   FallbackType
+	retryBudget    *retryBudget
+	readLatency    *latencyWindow
+	metrics        *metrics
+
+	lk                      sync.RWMutex
+	masterHealthChecker     HealthChecker
+	healthCheckTimeoutMilli int
+	healthCheckRise         int
+	healthCheckFall         int
+	circuitBreaker          CircuitBreaker
+	hedging                 Hedging
+	readyProbe              Probe
+	tracer                  Tracer
+	interceptors            []Interceptor
+	slowQueryThreshold      time.Duration
+	masterPool              PoolConfig
+	replicaPool             PoolConfig
+	nodePools               map[*sql.DB]PoolConfig
+	liveProbe               Probe
 }
 
 func New(master *sql.DB, readreplicas ...*sql.DB) *DB {
@@ -34,9 +70,21 @@ func New(master *sql.DB, readreplicas ...*sql.DB) *DB {
 	db := &DB{
 		ctx:            ctx,
 		master:         master,
+		masterNode:     newNode(Master, "master", master),
 		readreplicas:   readreplicas,
 		readDbBalancer: NewDbBalancer(ctx, readreplicas),
 		fallbackType:   DefaultFallbackType,
+		retryBudget:    newRetryBudget(DefaultRetryBudgetRatio),
+		readLatency:    newLatencyWindow(),
+		metrics:        newMetrics(),
+
+		masterHealthChecker:     PingChecker(),
+		healthCheckTimeoutMilli: DefaultHealthCheckTimeoutMilli,
+		healthCheckRise:         DefaultHealthCheckRise,
+		healthCheckFall:         DefaultHealthCheckFall,
+		readyProbe:              MasterUp(),
+		liveProbe:               AlwaysUp(),
+		nodePools:               make(map[*sql.DB]PoolConfig),
 	}
 
 	// setup context
@@ -54,7 +102,22 @@ func New(master *sql.DB, readreplicas ...*sql.DB) *DB {
 }
 
 func (db *DB) masterHealthCheck() {
-	db.masterHealth = db.master.Ping()
+	r := probe(
+		db.ctx,
+		db.GetMasterHealthChecker(),
+		db.master,
+		time.Duration(db.GetHealthCheckTimeoutMilli())*time.Millisecond,
+	)
+
+	db.lk.RLock()
+	rise, fall := db.healthCheckRise, db.healthCheckFall
+	db.lk.RUnlock()
+
+	if r.err != nil {
+		db.GetLogger().Warn("health check failed", "node", db.masterNode.Name(), "role", Master.String(), "err", r.err)
+	}
+	db.readDbBalancer.emit(db.masterNode.observeProbe(r, rise, fall, 0)...)
+	db.masterHealth = db.masterNode.health()
 }
 
 func (db *DB) masterHealthCheckWorker() {
@@ -70,33 +133,75 @@ func (db *DB) masterHealthCheckWorker() {
 }
 
 func (db *DB) getMaster() (*sql.DB, error) {
-	if db.masterHealth == nil {
-		return db.master, nil
-	} else {
+	if db.masterHealth != nil {
 		return nil, ErrMasterDied
 	}
+	if !db.masterNode.breaker.allow(db.GetCircuitBreaker(), time.Now()) {
+		return nil, ErrCircuitOpen
+	}
+	return db.master, nil
 }
 
-func (db *DB) getReadReplica() (*sql.DB, error) {
+func (db *DB) getReadReplica(ctx context.Context) (*sql.DB, error) {
+	err := ErrAllReadreplicaDied
 	if db.readDbBalancer.IsAlive() {
-		return db.readDbBalancer.Get(), nil
-	} else {
-		// Fallback. Use master for read, if all replica died
-		switch db.fallbackType {
-		case UseMaster:
-			if db.masterHealth == nil {
-				return db.master, nil
-			} else {
-				return nil, ErrMasterDied
-			}
-		default:
-			return nil, ErrAllReadreplicaDied
+		if span, ok := spanFromContext(ctx); ok {
+			balancer, localZone := db.readDbBalancer.decision(ctx)
+			span.SetAttribute("mydb.balancer", balancer)
+			span.SetAttribute("mydb.local_zone", localZone)
+		}
+		var d *sql.DB
+		d, err = db.readDbBalancer.GetContext(ctx)
+		// no readreplica is admitted, e.g. their half-open breakers have no trial left
+		if err != ErrCircuitOpen {
+			return d, err
 		}
 	}
+
+	// Fallback. Use master for read, if all replica died
+	switch db.fallbackType {
+	case UseMaster:
+		db.metrics.observeFallback()
+		db.GetLogger().Debug("read fell back to master")
+		setSpanAttribute(ctx, "mydb.fallback", true)
+		return db.getMaster()
+	default:
+		return nil, err
+	}
 }
 
+// track marks the start of op on d and returns the function
+// which marks its end with the call result.
+func (db *DB) track(d *sql.DB, op string) func(err error) {
+	if d != db.master {
+		n := db.readDbBalancer.nodes[d]
+		done := db.readDbBalancer.track(d)
+		start := time.Now()
+		return func(err error) {
+			done(err)
+			db.metrics.observeCall(n, op, err, time.Since(start))
+		}
+	}
+
+	n := db.masterNode
+	start := n.begin()
+	return func(err error) {
+		n.end(start)
+		now := time.Now()
+		latency := now.Sub(start)
+		n.breaker.observe(db.GetCircuitBreaker(), err, latency, now)
+		db.metrics.observeCall(n, op, err, latency)
+	}
+}
+
+// allDbList returns readreplicas and master. A nil master is skipped.
 func (db *DB) allDbList() []*sql.DB {
-	return append(db.readreplicas, db.master)
+	dbs := make([]*sql.DB, 0, len(db.readreplicas)+1)
+	dbs = append(dbs, db.readreplicas...)
+	if db.master != nil {
+		dbs = append(dbs, db.master)
+	}
+	return dbs
 }
 
 func (db *DB) Ping() error {
@@ -114,56 +219,93 @@ func (db *DB) PingContext(ctx context.Context) error {
 }
 
 func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
-	d, err := db.getReadReplica()
-	if err != nil {
-		return nil, err
-	}
-	return d.Query(query, args...)
+	return db.QueryContext(context.Background(), query, args...)
 }
 
 func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	d, err := db.getReadReplica()
+	ctx, span := db.startSpan(ctx, opQuery, query)
+	res, err := db.intercept(ctx, &Call{Method: "Query", Query: query, Args: args}, db.query)
+	// an interceptor may return neither rows nor an error
+	rows, ok := res.(*sql.Rows)
+	if err == nil && (!ok || rows == nil) {
+		err = ErrNilResult
+	}
+	endSpan(span, err)
 	if err != nil {
 		return nil, err
 	}
+	return rows, nil
+}
 
-	return d.QueryContext(ctx, query, args...)
+func (db *DB) query(ctx context.Context, call *Call) (interface{}, error) {
+	res, n, err := db.read(ctx, func(ctx context.Context, d *sql.DB) (interface{}, error) {
+		return d.QueryContext(ctx, call.Query, call.Args...)
+	})
+	db.metrics.observeRoute(true)
+	call.route(n)
+	return res, err
 }
 
 func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
-	d, err := db.getReadReplica()
-	if err != nil {
-		return nil
-	}
-
-	return d.QueryRow(query, args...)
+	return db.QueryRowContext(context.Background(), query, args...)
 }
 
 func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	d, err := db.getReadReplica()
-	if err != nil {
-		return nil
+	ctx, span := db.startSpan(ctx, opQuery, query)
+	res, err := db.intercept(ctx, &Call{Method: "QueryRow", Query: query, Args: args}, db.queryRow)
+	endSpan(span, err)
+
+	// return the routing error by Scan instead of a nil row
+	if row, ok := res.(*sql.Row); ok && row != nil {
+		return row
+	}
+	if err == nil {
+		err = ErrNilResult
 	}
+	return errRow(err)
+}
 
-	return d.QueryRowContext(ctx, query, args...)
+func (db *DB) queryRow(ctx context.Context, call *Call) (interface{}, error) {
+	res, n, err := db.read(ctx, func(ctx context.Context, d *sql.DB) (interface{}, error) {
+		row := d.QueryRowContext(ctx, call.Query, call.Args...)
+		return row, row.Err()
+	})
+	db.metrics.observeRoute(true)
+	call.route(n)
+	return res, err
 }
 
 func (db *DB) Begin() (*sql.Tx, error) {
-	d, err := db.getMaster()
+	return db.BeginTx(context.Background(), nil)
+}
+
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	ctx, span := db.startSpan(ctx, opBegin, "")
+	res, err := db.intercept(ctx, &Call{Method: "BeginTx", TxOptions: opts}, db.beginTx)
+	tx, ok := res.(*sql.Tx)
+	if err == nil && (!ok || tx == nil) {
+		err = ErrNilResult
+	}
+	endSpan(span, err)
 	if err != nil {
 		return nil, err
 	}
-
-	return d.Begin()
+	return tx, nil
 }
 
-func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+func (db *DB) beginTx(ctx context.Context, call *Call) (interface{}, error) {
 	d, err := db.getMaster()
 	if err != nil {
 		return nil, err
 	}
-
-	return d.BeginTx(ctx, opts)
+	traceNode(ctx, db.masterNode)
+	db.metrics.observeRoute(false)
+	call.route(db.masterNode)
+
+	done := db.track(d, opBegin)
+	tx, err := d.BeginTx(ctx, call.TxOptions)
+	done(err)
+	return tx, err
 }
 
 func (db *DB) Close() error {
@@ -179,49 +321,92 @@ func (db *DB) Close() error {
 }
 
 func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
-	d, err := db.getMaster()
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := db.startSpan(ctx, opExec, query)
+	res, err := db.intercept(ctx, &Call{Method: "Exec", Query: query, Args: args}, db.exec)
+	result, ok := res.(sql.Result)
+	if err == nil && !ok {
+		err = ErrNilResult
+	}
+	endSpan(span, err)
 	if err != nil {
 		return nil, err
 	}
-
-	return d.Exec(query, args...)
+	return result, nil
 }
 
-func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+func (db *DB) exec(ctx context.Context, call *Call) (interface{}, error) {
 	d, err := db.getMaster()
 	if err != nil {
 		return nil, err
 	}
-
-	return d.ExecContext(ctx, query, args...)
+	traceNode(ctx, db.masterNode)
+	db.metrics.observeRoute(false)
+	call.route(db.masterNode)
+
+	done := db.track(d, opExec)
+	result, err := d.ExecContext(ctx, call.Query, call.Args...)
+	done(err)
+	return result, err
 }
 
 func (db *DB) Prepare(query string) (*sql.Stmt, error) {
-	d, err := db.getMaster()
+	return db.PrepareContext(context.Background(), query)
+}
+
+func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := db.startSpan(ctx, opPrepare, query)
+	res, err := db.intercept(ctx, &Call{Method: "Prepare", Query: query}, db.prepare)
+	stmt, ok := res.(*sql.Stmt)
+	if err == nil && (!ok || stmt == nil) {
+		err = ErrNilResult
+	}
+	endSpan(span, err)
 	if err != nil {
 		return nil, err
 	}
-
-	return d.Prepare(query)
+	return stmt, nil
 }
 
-func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+func (db *DB) prepare(ctx context.Context, call *Call) (interface{}, error) {
 	d, err := db.getMaster()
 	if err != nil {
 		return nil, err
 	}
-
-	return d.PrepareContext(ctx, query)
+	traceNode(ctx, db.masterNode)
+	db.metrics.observeRoute(false)
+	call.route(db.masterNode)
+
+	done := db.track(d, opPrepare)
+	stmt, err := d.PrepareContext(ctx, call.Query)
+	done(err)
+	return stmt, err
 }
 
 func (db *DB) SetConnMaxLifetime(d time.Duration) {
+	db.logConfig("ConnMaxLifetime", d)
+
 	allDbList := db.allDbList()
 	for i := range allDbList {
 		allDbList[i].SetConnMaxLifetime(d)
 	}
 }
 
+func (db *DB) SetConnMaxIdleTime(d time.Duration) {
+	db.logConfig("ConnMaxIdleTime", d)
+
+	allDbList := db.allDbList()
+	for i := range allDbList {
+		allDbList[i].SetConnMaxIdleTime(d)
+	}
+}
+
 func (db *DB) SetMaxIdleConns(n int) {
+	db.logConfig("MaxIdleConns", n)
+
 	allDbList := db.allDbList()
 	for i := range allDbList {
 		allDbList[i].SetMaxIdleConns(n)
@@ -229,6 +414,8 @@ func (db *DB) SetMaxIdleConns(n int) {
 }
 
 func (db *DB) SetMaxOpenConns(n int) {
+	db.logConfig("MaxOpenConns", n)
+
 	allDbList := db.allDbList()
 	for i := range allDbList {
 		allDbList[i].SetMaxOpenConns(n)
@@ -240,14 +427,107 @@ func (db *DB) GetHealthCheckIntervalMilli() int {
 }
 
 func (db *DB) SetHealthCheckIntervalMilli(i int) {
+	db.logConfig("HealthCheckIntervalMilli", i)
+
 	db.readDbBalancer.SetHealthCheckIntervalMilli(i)
 }
 
+func (db *DB) GetHealthCheckTimeoutMilli() int {
+	db.lk.RLock()
+	defer db.lk.RUnlock()
+
+	return db.healthCheckTimeoutMilli
+}
+
+// SetHealthCheckTimeoutMilli sets the deadline of each health check of master and readreplicas.
+func (db *DB) SetHealthCheckTimeoutMilli(i int) {
+	db.logConfig("HealthCheckTimeoutMilli", i)
+
+	db.lk.Lock()
+	db.healthCheckTimeoutMilli = i
+	db.lk.Unlock()
+
+	db.readDbBalancer.SetHealthCheckTimeoutMilli(i)
+}
+
+func (db *DB) GetHealthCheckRise() int {
+	db.lk.RLock()
+	defer db.lk.RUnlock()
+
+	return db.healthCheckRise
+}
+
+// SetHealthCheckRise sets the number of consecutive successful health checks
+// needed for an unhealthy node to be used again.
+func (db *DB) SetHealthCheckRise(n int) {
+	db.logConfig("HealthCheckRise", n)
+
+	db.lk.Lock()
+	db.healthCheckRise = n
+	db.lk.Unlock()
+
+	db.readDbBalancer.SetHealthCheckRise(n)
+}
+
+func (db *DB) GetHealthCheckFall() int {
+	db.lk.RLock()
+	defer db.lk.RUnlock()
+
+	return db.healthCheckFall
+}
+
+// SetHealthCheckFall sets the number of consecutive failed health checks
+// needed for a healthy node to stop being used.
+func (db *DB) SetHealthCheckFall(n int) {
+	db.logConfig("HealthCheckFall", n)
+
+	db.lk.Lock()
+	db.healthCheckFall = n
+	db.lk.Unlock()
+
+	db.readDbBalancer.SetHealthCheckFall(n)
+}
+
+// HealthStates returns the health states of master and readreplicas.
+func (db *DB) HealthStates() []HealthState {
+	return append([]HealthState{db.masterNode.healthState(db.GetCircuitBreaker())}, db.readDbBalancer.HealthStates()...)
+}
+
+func (db *DB) GetOutlierDetection() OutlierDetection {
+	return db.readDbBalancer.GetOutlierDetection()
+}
+
+func (db *DB) SetOutlierDetection(o OutlierDetection) {
+	db.logConfig("OutlierDetection", o)
+
+	db.readDbBalancer.SetOutlierDetection(o)
+}
+
+func (db *DB) GetCircuitBreaker() CircuitBreaker {
+	db.lk.RLock()
+	defer db.lk.RUnlock()
+
+	return db.circuitBreaker
+}
+
+// SetCircuitBreaker sets the circuit breaker of master and readreplicas.
+func (db *DB) SetCircuitBreaker(c CircuitBreaker) {
+	db.logConfig("CircuitBreaker", c)
+
+	db.lk.Lock()
+	db.circuitBreaker = c
+	db.lk.Unlock()
+
+	db.readDbBalancer.SetCircuitBreaker(c)
+}
+
 func (db *DB) GetBalanceAlgorithm() BalanceAlgorithm {
 	return db.readDbBalancer.GetBalanceAlgorithm()
 }
 
 func (db *DB) SetBalanceAlgorithm(balanceAlgorithm BalanceAlgorithm) {
+	db.logConfig("BalanceAlgorithm", balanceAlgorithm)
+
 	db.readDbBalancer.SetBalanceAlgorithm(balanceAlgorithm)
 }
 
@@ -256,5 +536,223 @@ func (db *DB) GetFallbackType() FallbackType {
 }
 
 func (db *DB) SetFallbackType(fallbackType FallbackType) {
+	db.logConfig("FallbackType", fallbackType)
+
 	db.fallbackType = fallbackType
 }
+
+func (db *DB) GetAffinityLoadFactor() float64 {
+	return db.readDbBalancer.GetAffinityLoadFactor()
+}
+
+func (db *DB) SetAffinityLoadFactor(f float64) {
+	db.logConfig("AffinityLoadFactor", f)
+
+	db.readDbBalancer.SetAffinityLoadFactor(f)
+}
+
+func (db *DB) GetLocalZone() string {
+	return db.readDbBalancer.GetLocalZone()
+}
+
+func (db *DB) SetLocalZone(zone string) {
+	db.logConfig("LocalZone", zone)
+
+	db.readDbBalancer.SetLocalZone(zone)
+}
+
+func (db *DB) SetReplicaZone(readreplica *sql.DB, zone string) {
+	db.readDbBalancer.SetZone(readreplica, zone)
+}
+
+func (db *DB) GetLocalZoneMaxInflight() int64 {
+	return db.readDbBalancer.GetLocalZoneMaxInflight()
+}
+
+func (db *DB) SetLocalZoneMaxInflight(n int64) {
+	db.logConfig("LocalZoneMaxInflight", n)
+
+	db.readDbBalancer.SetLocalZoneMaxInflight(n)
+}
+
+func (db *DB) GetBalancer() Balancer {
+	return db.readDbBalancer.GetBalancer()
+}
+
+func (db *DB) SetBalancer(b Balancer) {
+	db.logConfig("Balancer", fmt.Sprintf("%T", b))
+
+	db.readDbBalancer.SetBalancer(b)
+}
+
+func (db *DB) GetMasterHealthChecker() HealthChecker {
+	db.lk.RLock()
+	defer db.lk.RUnlock()
+
+	return db.masterHealthChecker
+}
+
+func (db *DB) SetMasterHealthChecker(hc HealthChecker) {
+	db.logConfig("MasterHealthChecker", fmt.Sprintf("%T", hc))
+
+	db.lk.Lock()
+	defer db.lk.Unlock()
+
+	db.masterHealthChecker = hc
+}
+
+func (db *DB) GetReplicaHealthChecker() HealthChecker {
+	return db.readDbBalancer.GetHealthChecker()
+}
+
+func (db *DB) SetReplicaHealthChecker(hc HealthChecker) {
+	db.logConfig("ReplicaHealthChecker", fmt.Sprintf("%T", hc))
+
+	db.readDbBalancer.SetHealthChecker(hc)
+}
+
+func (db *DB) GetRetryBudgetRatio() float64 {
+	return db.retryBudget.GetRatio()
+}
+
+// SetRetryBudgetRatio sets the maximum ratio of retried reads to all reads. 0 disables retries.
+func (db *DB) SetRetryBudgetRatio(ratio float64) {
+	db.logConfig("RetryBudgetRatio", ratio)
+
+	db.retryBudget.SetRatio(ratio)
+}
+
+func (db *DB) GetHedging() Hedging {
+	db.lk.RLock()
+	defer db.lk.RUnlock()
+
+	return db.hedging
+}
+
+func (db *DB) SetHedging(h Hedging) {
+	db.logConfig("Hedging", h)
+
+	db.lk.Lock()
+	defer db.lk.Unlock()
+
+	db.hedging = h
+}
+
+func (db *DB) GetReadyProbe() Probe {
+	db.lk.RLock()
+	defer db.lk.RUnlock()
+
+	return db.readyProbe
+}
+
+// SetReadyProbe sets the probe of /ready of StatusHandler.
+// nil resets it to MasterUp.
+func (db *DB) SetReadyProbe(p Probe) {
+	db.logConfig("ReadyProbe", fmt.Sprintf("%T", p))
+
+	if p == nil {
+		p = MasterUp()
+	}
+
+	db.lk.Lock()
+	defer db.lk.Unlock()
+
+	db.readyProbe = p
+}
+
+func (db *DB) GetLiveProbe() Probe {
+	db.lk.RLock()
+	defer db.lk.RUnlock()
+
+	return db.liveProbe
+}
+
+// SetLiveProbe sets the probe of /live of StatusHandler.
+// nil resets it to AlwaysUp.
+func (db *DB) SetLiveProbe(p Probe) {
+	db.logConfig("LiveProbe", fmt.Sprintf("%T", p))
+
+	if p == nil {
+		p = AlwaysUp()
+	}
+
+	db.lk.Lock()
+	defer db.lk.Unlock()
+
+	db.liveProbe = p
+}
+
+func (db *DB) GetTracer() Tracer {
+	db.lk.RLock()
+	defer db.lk.RUnlock()
+
+	return db.tracer
+}
+
+// SetTracer sets the tracer of Query, Exec, Prepare and Begin. nil disables tracing.
+func (db *DB) SetTracer(t Tracer) {
+	db.logConfig("Tracer", fmt.Sprintf("%T", t))
+
+	db.lk.Lock()
+	defer db.lk.Unlock()
+
+	db.tracer = t
+}
+
+func (db *DB) GetMaxReplicationLag() time.Duration {
+	return db.readDbBalancer.GetMaxReplicationLag()
+}
+
+// SetMaxReplicationLag sets the replication lag over which LagExceeded is emitted. 0 disables it.
+// The lag is measured if the readreplica health checker implements LagChecker.
+func (db *DB) SetMaxReplicationLag(lag time.Duration) {
+	db.logConfig("MaxReplicationLag", lag)
+
+	db.readDbBalancer.SetMaxReplicationLag(lag)
+}
+
+// OnEvent registers f, which is called for every topology event.
+// f is called on the goroutine which detected the event: a health check worker,
+// or a routed call which ejected a readreplica or opened its circuit breaker.
+// So f must not block. It may call methods of db.
+func (db *DB) OnEvent(f func(Event)) {
+	db.readDbBalancer.events.OnEvent(f)
+}
+
+// Watch returns a channel receiving topology events until ctx is done.
+// Events are dropped while the channel buffer is full.
+func (db *DB) Watch(ctx context.Context) <-chan Event {
+	return db.readDbBalancer.events.Watch(ctx)
+}
+
+func (db *DB) logConfig(name string, value interface{}) {
+	db.GetLogger().Info("config changed", "name", name, "value", value)
+}
+
+func (db *DB) GetLogger() Logger {
+	return db.readDbBalancer.GetLogger()
+}
+
+// SetLogger sets the logger of health checks, routing and configuration changes.
+// nil discards all messages.
+func (db *DB) SetLogger(l Logger) {
+	db.readDbBalancer.SetLogger(l)
+}
+
+func (db *DB) GetSlowQueryThreshold() time.Duration {
+	db.lk.RLock()
+	defer db.lk.RUnlock()
+
+	return db.slowQueryThreshold
+}
+
+// SetSlowQueryThreshold sets the duration over which routed calls are logged as slow queries.
+// 0 disables the slow query log.
+func (db *DB) SetSlowQueryThreshold(d time.Duration) {
+	db.logConfig("SlowQueryThreshold", d)
+
+	db.lk.Lock()
+	defer db.lk.Unlock()
+
+	db.slowQueryThreshold = d
+}
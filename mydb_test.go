@@ -325,6 +325,34 @@ func TestQueryRow(t *testing.T) {
 			t.Errorf("there were unfulfilled expectations: %s", err)
 		}
 	})
+
+	t.Run("error with all readreplica died", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		db := New(master, readreplica)
+		defer db.Close()
+		db.SetFallbackType(None)
+		db.readDbBalancer.availableDbs.Replace(nil)
+
+		row := db.QueryRow("select 1")
+		if row == nil {
+			t.Fatal("QueryRow() want non nil row")
+		}
+		if err := row.Err(); err != ErrAllReadreplicaDied {
+			t.Errorf("Err() want %s, but get %v", ErrAllReadreplicaDied, err)
+		}
+		var id int
+		if err := row.Scan(&id); err != ErrAllReadreplicaDied {
+			t.Errorf("Scan() want %s, but get %v", ErrAllReadreplicaDied, err)
+		}
+	})
 }
 
 func TestQueryRowContext(t *testing.T) {
@@ -618,6 +646,83 @@ func TestHealthCheckIntervalMilli(t *testing.T) {
 	})
 }
 
+func TestHealthCheckTimeoutMilli(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		db := New(master)
+		defer db.Close()
+
+		if db.GetHealthCheckTimeoutMilli() != DefaultHealthCheckTimeoutMilli {
+			t.Errorf("GetHealthCheckTimeoutMilli() want %d", DefaultHealthCheckTimeoutMilli)
+		}
+
+		db.SetHealthCheckTimeoutMilli(200)
+		if db.GetHealthCheckTimeoutMilli() != 200 {
+			t.Error("GetHealthCheckTimeoutMilli() want 200")
+		}
+		if db.readDbBalancer.GetHealthCheckTimeoutMilli() != 200 {
+			t.Error("readDbBalancer GetHealthCheckTimeoutMilli() want 200")
+		}
+	})
+}
+
+func TestHealthCheckRiseFall(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, readreplicaMock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		// initial health check
+		masterMock.ExpectPing()
+		readreplicaMock.ExpectPing()
+
+		db := New(master, readreplica)
+		defer db.Close()
+		db.SetHealthCheckRise(2)
+		db.SetHealthCheckFall(2)
+		if db.GetHealthCheckRise() != 2 || db.GetHealthCheckFall() != 2 {
+			t.Error("GetHealthCheckRise() and GetHealthCheckFall() want 2")
+		}
+
+		// a single failure keeps readreplica in rotation
+		readreplicaMock.ExpectPing().WillReturnError(errors.New("ping error"))
+		db.readDbBalancer.healthCheck()
+		if !db.readDbBalancer.IsAlive() {
+			t.Error("IsAlive() want true")
+		}
+
+		// second failure removes readreplica
+		readreplicaMock.ExpectPing().WillReturnError(errors.New("ping error"))
+		db.readDbBalancer.healthCheck()
+		if db.readDbBalancer.IsAlive() {
+			t.Error("IsAlive() want false")
+		}
+
+		states := db.HealthStates()
+		if len(states) != 2 {
+			t.Fatalf("HealthStates() want %d states, but get %d", 2, len(states))
+		}
+		if states[0].Role != Master || !states[0].Healthy {
+			t.Error("HealthStates() want healthy master")
+		}
+		if states[1].Role != ReadReplica || states[1].Healthy || states[1].ConsecutiveFailures != 2 {
+			t.Errorf("HealthStates() want unhealthy readreplica, but get %+v", states[1])
+		}
+
+		if err := readreplicaMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
+
 func TestBalanceAlgorithm(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		master, _, err := sqlmock.New()
@@ -758,3 +863,93 @@ func TestFallbackWithNone(t *testing.T) {
 		}
 	})
 }
+
+func TestAffinityLoadFactor(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		db := New(master)
+		defer db.Close()
+
+		if db.GetAffinityLoadFactor() != DefaultAffinityLoadFactor {
+			t.Errorf("GetAffinityLoadFactor() want %f", DefaultAffinityLoadFactor)
+		}
+
+		db.SetAffinityLoadFactor(2)
+		if db.GetAffinityLoadFactor() != 2 {
+			t.Error("GetAffinityLoadFactor() want 2")
+		}
+	})
+}
+
+func TestLocalZone(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		db := New(master, readreplica)
+		defer db.Close()
+
+		if db.GetLocalZone() != "" {
+			t.Error("GetLocalZone() want empty")
+		}
+
+		db.SetReplicaZone(readreplica, "zone-a")
+		db.SetLocalZone("zone-a")
+		if db.GetLocalZone() != "zone-a" {
+			t.Error("GetLocalZone() want zone-a")
+		}
+		if db.readDbBalancer.localDbs.IsEmpty() {
+			t.Error("local dbs want readreplica")
+		}
+
+		db.SetLocalZoneMaxInflight(10)
+		if db.GetLocalZoneMaxInflight() != 10 {
+			t.Error("GetLocalZoneMaxInflight() want 10")
+		}
+	})
+}
+
+func TestHealthChecker(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, readreplicaMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		db := New(master, readreplica)
+		defer db.Close()
+
+		if db.GetMasterHealthChecker() == nil {
+			t.Error("GetMasterHealthChecker() want default checker")
+		}
+
+		db.SetMasterHealthChecker(ReadOnlyChecker(false))
+		db.SetReplicaHealthChecker(ReplicationChecker())
+		if db.GetReplicaHealthChecker() == nil {
+			t.Error("GetReplicaHealthChecker() want checker")
+		}
+
+		// SQL thread is stopped
+		readreplicaMock.ExpectQuery("SHOW REPLICA STATUS").
+			WillReturnRows(sqlmock.NewRows([]string{"Replica_IO_Running", "Replica_SQL_Running"}).AddRow("Yes", "No"))
+		db.readDbBalancer.healthCheck()
+
+		if db.readDbBalancer.IsAlive() {
+			t.Error("IsAlive() want false")
+		}
+		if err := readreplicaMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
@@ -0,0 +1,274 @@
+package mydb
+
+import (
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Role int
+
+const (
+	Master Role = iota
+	ReadReplica
+)
+
+func (r Role) String() string {
+	switch r {
+	case Master:
+		return "master"
+	case ReadReplica:
+		return "readreplica"
+	default:
+		return "unknown"
+	}
+}
+
+// HealthState is the state of active health checks of a node.
+type HealthState struct {
+	Name                 string
+	Role                 Role
+	Healthy              bool
+	ConsecutiveSuccesses int
+	ConsecutiveFailures  int
+	Breaker              BreakerState
+}
+
+var _ interface {
+	Name() string
+	Role() Role
+	Zone() string
+	DB() *sql.DB
+	Inflight() int64
+	Latency() time.Duration
+} = newNode(ReadReplica, "", nil)
+
+// latencyDecay is the weight divisor of the latency EWMA.
+// A new sample moves the score by 1/latencyDecay of the difference.
+const latencyDecay = 8
+
+type node struct {
+	db       *sql.DB
+	role     Role
+	name     string
+	inflight int64
+	latency  int64 // EWMA of call latency in nanoseconds
+
+	lk                   sync.RWMutex
+	zone                 string
+	checked              bool
+	healthy              bool
+	healthErr            error
+	consecutiveSuccesses int
+	consecutiveFailures  int
+	lastProbe            time.Time
+	probeLatency         time.Duration
+	lag                  time.Duration
+	hasLag               bool
+	lagExceeded          bool
+	identity             string
+
+	// passive health check
+	requests              int
+	connErrors            int
+	consecutiveConnErrors int
+	ejectedUntil          time.Time
+
+	breaker breaker
+}
+
+func newNode(role Role, name string, db *sql.DB) *node {
+	return &node{
+		db:   db,
+		role: role,
+		name: name,
+	}
+}
+
+func (n *node) begin() time.Time {
+	atomic.AddInt64(&n.inflight, 1)
+	return time.Now()
+}
+
+func (n *node) end(start time.Time) {
+	atomic.AddInt64(&n.inflight, -1)
+	n.observeLatency(time.Since(start))
+}
+
+func (n *node) observeLatency(d time.Duration) {
+	for {
+		old := atomic.LoadInt64(&n.latency)
+		next := int64(d)
+		if old != 0 {
+			next = old + (int64(d)-old)/latencyDecay
+		}
+		if atomic.CompareAndSwapInt64(&n.latency, old, next) {
+			return
+		}
+	}
+}
+
+func (n *node) Name() string {
+	return n.name
+}
+
+func (n *node) Role() Role {
+	return n.role
+}
+
+func (n *node) DB() *sql.DB {
+	return n.db
+}
+
+func (n *node) Zone() string {
+	n.lk.RLock()
+	defer n.lk.RUnlock()
+
+	return n.zone
+}
+
+func (n *node) setZone(zone string) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	n.zone = zone
+}
+
+func (n *node) Inflight() int64 {
+	return atomic.LoadInt64(&n.inflight)
+}
+
+func (n *node) Latency() time.Duration {
+	return time.Duration(atomic.LoadInt64(&n.latency))
+}
+
+// load returns the outstanding requests of n used for balancing.
+// Inflight ends when a query returns, while rows still being read keep
+// their connection in use, so the larger of Inflight and the connections in use is taken.
+func (n *node) load() int64 {
+	l := n.Inflight()
+	if n.db != nil {
+		if inUse := int64(n.db.Stats().InUse); inUse > l {
+			l = inUse
+		}
+	}
+	return l
+}
+
+// lessLoaded reports whether n is a better choice than o.
+// Lower load wins, lower latency breaks ties.
+func (n *node) lessLoaded(o *node) bool {
+	ni, oi := n.load(), o.load()
+	if ni != oi {
+		return ni < oi
+	}
+	return n.Latency() < o.Latency()
+}
+
+// observeHealth records a health check result and reports whether the node
+// changed between healthy and unhealthy.
+// The first result decides the state directly. After that, rise consecutive successes
+// are needed to become healthy and fall consecutive failures to become unhealthy.
+func (n *node) observeHealth(err error, rise, fall int) bool {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	if err == nil {
+		n.consecutiveSuccesses++
+		n.consecutiveFailures = 0
+	} else {
+		n.consecutiveFailures++
+		n.consecutiveSuccesses = 0
+		n.healthErr = err
+	}
+
+	switch {
+	case !n.checked:
+		n.checked = true
+		n.healthy = err == nil
+	case !n.healthy && n.consecutiveSuccesses >= rise:
+		n.healthy = true
+		return true
+	case n.healthy && n.consecutiveFailures >= fall:
+		n.healthy = false
+		return true
+	}
+	return false
+}
+
+// observeProbe records a health check result with its timing, lag and identity,
+// and returns the events caused by it. maxLag 0 disables LagExceeded.
+func (n *node) observeProbe(r probeResult, rise, fall int, maxLag time.Duration) []Event {
+	var events []Event
+
+	n.lk.Lock()
+	n.lastProbe = r.at
+	n.probeLatency = r.latency
+	n.lag, n.hasLag = r.lag, r.hasLag
+
+	if r.hasLag && maxLag > 0 {
+		exceeded := r.lag > maxLag
+		if exceeded && !n.lagExceeded {
+			events = append(events, Event{Type: LagExceeded, Lag: r.lag})
+		}
+		n.lagExceeded = exceeded
+	}
+
+	if r.identity != "" {
+		if n.role == Master && n.identity != "" && n.identity != r.identity {
+			events = append(events, Event{Type: MasterChanged, Identity: r.identity, PreviousIdentity: n.identity})
+		}
+		n.identity = r.identity
+	}
+	n.lk.Unlock()
+
+	if n.observeHealth(r.err, rise, fall) {
+		if r.err == nil {
+			events = append(events, Event{Type: NodeUp})
+		} else {
+			events = append(events, Event{Type: NodeDown, Err: r.err})
+		}
+	}
+
+	for i := range events {
+		events[i].Time = r.at
+		events[i].Node = n.name
+		events[i].Role = n.role
+	}
+	return events
+}
+
+func (n *node) isHealthy() bool {
+	n.lk.RLock()
+	defer n.lk.RUnlock()
+
+	return n.healthy
+}
+
+// health returns nil if the node is healthy, otherwise the last health check error.
+func (n *node) health() error {
+	n.lk.RLock()
+	defer n.lk.RUnlock()
+
+	if n.healthy {
+		return nil
+	}
+	return n.healthErr
+}
+
+func (n *node) healthState(c CircuitBreaker) HealthState {
+	breakerState := n.breaker.State(c, time.Now())
+
+	n.lk.RLock()
+	defer n.lk.RUnlock()
+
+	return HealthState{
+		Name:                 n.name,
+		Role:                 n.role,
+		Healthy:              n.healthy,
+		ConsecutiveSuccesses: n.consecutiveSuccesses,
+		ConsecutiveFailures:  n.consecutiveFailures,
+		Breaker:              breakerState,
+	}
+}
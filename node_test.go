@@ -0,0 +1,161 @@
+package mydb
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestNodeBeginEnd(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		n := newNode(ReadReplica, "", nil)
+
+		start := n.begin()
+		if n.Inflight() != 1 {
+			t.Errorf("Inflight() want %d, but get %d", 1, n.Inflight())
+		}
+
+		n.end(start)
+		if n.Inflight() != 0 {
+			t.Errorf("Inflight() want %d, but get %d", 0, n.Inflight())
+		}
+		if n.Latency() <= 0 {
+			t.Error("Latency() want positive value")
+		}
+	})
+}
+
+func TestNodeObserveLatency(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		n := newNode(ReadReplica, "", nil)
+
+		n.observeLatency(80 * time.Millisecond)
+		if n.Latency() != 80*time.Millisecond {
+			t.Errorf("Latency() want %s, but get %s", 80*time.Millisecond, n.Latency())
+		}
+
+		n.observeLatency(0)
+		if n.Latency() != 70*time.Millisecond {
+			t.Errorf("Latency() want %s, but get %s", 70*time.Millisecond, n.Latency())
+		}
+	})
+}
+
+func TestNodeLessLoaded(t *testing.T) {
+	t.Run("success with inflight", func(t *testing.T) {
+		n0 := newNode(ReadReplica, "", nil)
+		n1 := newNode(ReadReplica, "", nil)
+		n0.begin()
+
+		if !n1.lessLoaded(n0) {
+			t.Error("lessLoaded() want true")
+		}
+		if n0.lessLoaded(n1) {
+			t.Error("lessLoaded() want false")
+		}
+	})
+
+	t.Run("success with open rows", func(t *testing.T) {
+		db0, mock0, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		defer db0.Close()
+		mock0.ExpectQuery("select 1").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+
+		n0 := newNode(ReadReplica, "", db0)
+		n1 := newNode(ReadReplica, "", nil)
+
+		// the request has ended, but its rows are still being read
+		start := n0.begin()
+		rows, err := db0.Query("select 1")
+		n0.end(start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		if n0.load() != 1 {
+			t.Errorf("load() want %d, but get %d", 1, n0.load())
+		}
+		if !n1.lessLoaded(n0) {
+			t.Error("lessLoaded() want true")
+		}
+	})
+
+	t.Run("success with latency", func(t *testing.T) {
+		n0 := newNode(ReadReplica, "", nil)
+		n1 := newNode(ReadReplica, "", nil)
+		n0.observeLatency(20 * time.Millisecond)
+		n1.observeLatency(10 * time.Millisecond)
+
+		if !n1.lessLoaded(n0) {
+			t.Error("lessLoaded() want true")
+		}
+	})
+}
+
+func TestNodeObserveHealth(t *testing.T) {
+	checkErr := errors.New("check error")
+
+	t.Run("success with first check", func(t *testing.T) {
+		n := newNode(ReadReplica, "", nil)
+		n.observeHealth(checkErr, 2, 3)
+		if n.isHealthy() {
+			t.Error("isHealthy() want false")
+		}
+		if n.health() != checkErr {
+			t.Errorf("health() want %s, but get %v", checkErr, n.health())
+		}
+	})
+
+	t.Run("success with fall", func(t *testing.T) {
+		n := newNode(ReadReplica, "", nil)
+		n.observeHealth(nil, 2, 3)
+
+		for i := 0; i < 2; i++ {
+			n.observeHealth(checkErr, 2, 3)
+			if !n.isHealthy() {
+				t.Errorf("isHealthy() want true after %d failures", i+1)
+			}
+		}
+		n.observeHealth(checkErr, 2, 3)
+		if n.isHealthy() {
+			t.Error("isHealthy() want false after 3 failures")
+		}
+	})
+
+	t.Run("success with rise", func(t *testing.T) {
+		n := newNode(ReadReplica, "", nil)
+		n.observeHealth(checkErr, 2, 3)
+
+		n.observeHealth(nil, 2, 3)
+		if n.isHealthy() {
+			t.Error("isHealthy() want false after 1 success")
+		}
+		n.observeHealth(nil, 2, 3)
+		if !n.isHealthy() {
+			t.Error("isHealthy() want true after 2 successes")
+		}
+	})
+
+	t.Run("success with flapping", func(t *testing.T) {
+		n := newNode(ReadReplica, "", nil)
+		n.observeHealth(nil, 2, 3)
+
+		for i := 0; i < 5; i++ {
+			n.observeHealth(checkErr, 2, 3)
+			n.observeHealth(nil, 2, 3)
+		}
+		state := n.healthState(CircuitBreaker{})
+		if !state.Healthy {
+			t.Error("Healthy want true")
+		}
+		if state.ConsecutiveSuccesses != 1 || state.ConsecutiveFailures != 0 {
+			t.Errorf("counters want 1 and 0, but get %d and %d", state.ConsecutiveSuccesses, state.ConsecutiveFailures)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package mydb
+
+import (
+	"regexp"
+	"strings"
+)
+
+var inListPattern = regexp.MustCompile(`(?i)\bIN \(\?(?:, ?\?)*\)`)
+
+// normalizeSQL replaces literals in query with ? and collapses whitespace,
+// so that queries differing only in values have the same form.
+// e.g. "SELECT * FROM t WHERE id IN (1, 2)  AND name = 'a'"
+// becomes "SELECT * FROM t WHERE id IN (?) AND name = ?".
+func normalizeSQL(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	space := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			continue
+		case c == '\'' || c == '"':
+			i = skipQuoted(query, i)
+			c = '?'
+		case c == '`':
+			end := skipQuoted(query, i)
+			writeSpace(&b, &space)
+			b.WriteString(query[i : end+1])
+			i = end
+			continue
+		case isDigit(c) && (i == 0 || !isIdentChar(query[i-1])):
+			for i+1 < len(query) && (isIdentChar(query[i+1]) || query[i+1] == '.') {
+				i++
+			}
+			c = '?'
+		}
+
+		writeSpace(&b, &space)
+		b.WriteByte(c)
+	}
+
+	return inListPattern.ReplaceAllString(b.String(), "IN (?)")
+}
+
+// skipQuoted returns the index of the quote closing the one at start.
+// Backslash escapes and doubled quotes are skipped.
+func skipQuoted(s string, start int) int {
+	quote := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return len(s) - 1
+}
+
+func writeSpace(b *strings.Builder, space *bool) {
+	if *space && b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	*space = false
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_' || c == '$'
+}
@@ -0,0 +1,23 @@
+package mydb
+
+import "testing"
+
+func TestNormalizeSQL(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT * FROM code WHERE id = 1", "SELECT * FROM code WHERE id = ?"},
+		{"SELECT *\n\tFROM  code\nWHERE name = 'a''b' AND note = \"c\\\"d\"", "SELECT * FROM code WHERE name = ? AND note = ?"},
+		{"SELECT * FROM t1 WHERE id IN (1, 2, 3) AND v > 1.5", "SELECT * FROM t1 WHERE id IN (?) AND v > ?"},
+		{"SELECT * FROM t WHERE id in (?,?)", "SELECT * FROM t WHERE id IN (?)"},
+		{"SELECT `col 1` FROM `t2` LIMIT 10", "SELECT `col 1` FROM `t2` LIMIT ?"},
+		{"  INSERT INTO code VALUES (?, ?)  ", "INSERT INTO code VALUES (?, ?)"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeSQL(tt.query); got != tt.want {
+			t.Errorf("normalizeSQL(%q) want %q, but get %q", tt.query, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package mydb
+
+import (
+	"time"
+)
+
+const (
+	DefaultOutlierEjectionTime = 30 * time.Second
+	DefaultOutlierMinRequests  = 10
+)
+
+// OutlierDetection configures passive health checking of readreplicas.
+// A readreplica is ejected from rotation immediately when connection level errors
+// of routed reads exceed a threshold, and is re-admitted by the active health check
+// once the ejection time has passed.
+// The zero value disables outlier detection.
+type OutlierDetection struct {
+	// ConsecutiveErrors ejects a readreplica after this many consecutive connection errors.
+	// 0 disables the check.
+	ConsecutiveErrors int
+	// ErrorRate ejects a readreplica when the rate of connection errors
+	// within a health check interval reaches this value. 0 disables the check.
+	ErrorRate float64
+	// MinRequests is the number of requests within a health check interval
+	// needed to evaluate ErrorRate. DefaultOutlierMinRequests is used if 0.
+	MinRequests int
+	// EjectionTime is the minimum time an ejected readreplica stays out of rotation.
+	// DefaultOutlierEjectionTime is used if 0.
+	EjectionTime time.Duration
+}
+
+func (o OutlierDetection) enabled() bool {
+	return o.ConsecutiveErrors > 0 || o.ErrorRate > 0
+}
+
+func (o OutlierDetection) minRequests() int {
+	if o.MinRequests <= 0 {
+		return DefaultOutlierMinRequests
+	}
+	return o.MinRequests
+}
+
+func (o OutlierDetection) ejectionTime() time.Duration {
+	if o.EjectionTime <= 0 {
+		return DefaultOutlierEjectionTime
+	}
+	return o.EjectionTime
+}
+
+// observeOutlier records the result of a routed call and reports whether
+// the node has just been ejected.
+func (n *node) observeOutlier(err error, o OutlierDetection, now time.Time) bool {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	n.requests++
+	if !isConnError(err) {
+		n.consecutiveConnErrors = 0
+		return false
+	}
+	n.connErrors++
+	n.consecutiveConnErrors++
+
+	if n.ejectedUntil.After(now) {
+		return false
+	}
+
+	exceeded := o.ConsecutiveErrors > 0 && n.consecutiveConnErrors >= o.ConsecutiveErrors
+	if o.ErrorRate > 0 && n.requests >= o.minRequests() {
+		exceeded = exceeded || float64(n.connErrors)/float64(n.requests) >= o.ErrorRate
+	}
+	if !exceeded {
+		return false
+	}
+
+	n.ejectedUntil = now.Add(o.ejectionTime())
+	n.consecutiveConnErrors = 0
+	return true
+}
+
+// resetOutlierWindow starts a new window for ErrorRate.
+func (n *node) resetOutlierWindow() {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	n.requests = 0
+	n.connErrors = 0
+}
+
+func (n *node) isEjected(now time.Time) bool {
+	n.lk.RLock()
+	defer n.lk.RUnlock()
+
+	return n.ejectedUntil.After(now)
+}
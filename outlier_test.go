@@ -0,0 +1,136 @@
+package mydb
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestNodeObserveOutlier(t *testing.T) {
+	connErr := errors.New("invalid connection")
+	now := time.Now()
+
+	t.Run("success with consecutive errors", func(t *testing.T) {
+		n := newNode(ReadReplica, "", nil)
+		o := OutlierDetection{ConsecutiveErrors: 2}
+
+		if n.observeOutlier(connErr, o, now) {
+			t.Error("observeOutlier() want false after 1 error")
+		}
+		if !n.observeOutlier(connErr, o, now) {
+			t.Error("observeOutlier() want true after 2 errors")
+		}
+		if !n.isEjected(now) {
+			t.Error("isEjected() want true")
+		}
+		if n.isEjected(now.Add(DefaultOutlierEjectionTime)) {
+			t.Error("isEjected() want false after ejection time")
+		}
+	})
+
+	t.Run("success with error rate", func(t *testing.T) {
+		n := newNode(ReadReplica, "", nil)
+		o := OutlierDetection{ErrorRate: 0.5, MinRequests: 4, EjectionTime: time.Second}
+
+		n.observeOutlier(nil, o, now)
+		n.observeOutlier(connErr, o, now)
+		n.observeOutlier(nil, o, now)
+		if !n.observeOutlier(connErr, o, now) {
+			t.Error("observeOutlier() want true at error rate 0.5")
+		}
+		if n.isEjected(now.Add(time.Second)) {
+			t.Error("isEjected() want false after ejection time")
+		}
+	})
+
+	t.Run("success with default min requests", func(t *testing.T) {
+		n := newNode(ReadReplica, "", nil)
+		o := OutlierDetection{ErrorRate: 0.5}
+
+		for i := 0; i < DefaultOutlierMinRequests-1; i++ {
+			if n.observeOutlier(connErr, o, now) {
+				t.Fatalf("observeOutlier() want false before %d requests", DefaultOutlierMinRequests)
+			}
+		}
+		if !n.observeOutlier(connErr, o, now) {
+			t.Errorf("observeOutlier() want true at %d requests", DefaultOutlierMinRequests)
+		}
+	})
+
+	t.Run("success with reset window", func(t *testing.T) {
+		n := newNode(ReadReplica, "", nil)
+		o := OutlierDetection{ErrorRate: 0.5, MinRequests: 2}
+
+		n.observeOutlier(connErr, o, now)
+		n.resetOutlierWindow()
+		if n.observeOutlier(nil, o, now) {
+			t.Error("observeOutlier() want false in new window")
+		}
+	})
+
+	t.Run("success with statement error", func(t *testing.T) {
+		n := newNode(ReadReplica, "", nil)
+		o := OutlierDetection{ConsecutiveErrors: 1}
+
+		if n.observeOutlier(errors.New("Error 1064: syntax error"), o, now) {
+			t.Error("observeOutlier() want false with statement error")
+		}
+	})
+}
+
+func TestOutlierDetection(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica0, readreplica0Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1, readreplica1Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1Mock.ExpectQuery("select 1").
+			WillReturnError(errors.New("invalid connection"))
+		for i := 0; i < 3; i++ {
+			readreplica0Mock.ExpectQuery("select 1").
+				WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+		}
+
+		db := New(master, readreplica0, readreplica1)
+		defer db.Close()
+		db.SetBalanceAlgorithm(RoundRobin)
+		db.SetRetryBudgetRatio(0)
+		db.SetOutlierDetection(OutlierDetection{ConsecutiveErrors: 1})
+		if db.GetOutlierDetection().ConsecutiveErrors != 1 {
+			t.Error("GetOutlierDetection() want ConsecutiveErrors 1")
+		}
+
+		// readreplica1 loses connection and is ejected
+		if _, err = db.Query("select 1"); err == nil {
+			t.Error("Query() want error")
+		}
+		for i := 0; i < 3; i++ {
+			if _, err = db.Query("select 1"); err != nil {
+				t.Error(err)
+			}
+		}
+
+		// the active health check does not re-admit readreplica1 within the ejection time
+		db.readDbBalancer.healthCheck()
+		if len(db.readDbBalancer.availableDbs.List()) != 1 {
+			t.Error("available dbs want only readreplica0")
+		}
+
+		if err := readreplica0Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+		if err := readreplica1Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
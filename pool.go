@@ -0,0 +1,113 @@
+package mydb
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PoolConfig is the connection pool configuration of a db.
+// Zero fields are left unchanged. A negative value has the meaning of 0 in database/sql,
+// e.g. MaxOpenConns -1 removes the limit and MaxIdleConns -1 retains no idle connection.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// merge returns c overridden by the non-zero fields of o.
+func (c PoolConfig) merge(o PoolConfig) PoolConfig {
+	if o.MaxOpenConns != 0 {
+		c.MaxOpenConns = o.MaxOpenConns
+	}
+	if o.MaxIdleConns != 0 {
+		c.MaxIdleConns = o.MaxIdleConns
+	}
+	if o.ConnMaxLifetime != 0 {
+		c.ConnMaxLifetime = o.ConnMaxLifetime
+	}
+	if o.ConnMaxIdleTime != 0 {
+		c.ConnMaxIdleTime = o.ConnMaxIdleTime
+	}
+	return c
+}
+
+func (c PoolConfig) apply(d *sql.DB) {
+	// MaxIdleConns is set after MaxOpenConns, because database/sql
+	// reduces the idle connections to the open connections limit.
+	if c.MaxOpenConns != 0 {
+		d.SetMaxOpenConns(c.MaxOpenConns)
+	}
+	if c.MaxIdleConns != 0 {
+		d.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	if c.ConnMaxLifetime != 0 {
+		d.SetConnMaxLifetime(c.ConnMaxLifetime)
+	}
+	if c.ConnMaxIdleTime != 0 {
+		d.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+	}
+}
+
+func (db *DB) GetMasterPool() PoolConfig {
+	db.lk.RLock()
+	defer db.lk.RUnlock()
+
+	return db.masterPool
+}
+
+// SetMasterPool sets the connection pool of master. Overrides by SetNodePool take precedence.
+func (db *DB) SetMasterPool(p PoolConfig) {
+	db.logConfig("MasterPool", p)
+
+	db.lk.Lock()
+	defer db.lk.Unlock()
+
+	db.masterPool = db.masterPool.merge(p)
+	db.applyPool(db.master)
+}
+
+func (db *DB) GetReplicaPool() PoolConfig {
+	db.lk.RLock()
+	defer db.lk.RUnlock()
+
+	return db.replicaPool
+}
+
+// SetReplicaPool sets the connection pool of all readreplicas. Overrides by SetNodePool take precedence.
+func (db *DB) SetReplicaPool(p PoolConfig) {
+	db.logConfig("ReplicaPool", p)
+
+	db.lk.Lock()
+	defer db.lk.Unlock()
+
+	db.replicaPool = db.replicaPool.merge(p)
+	for i := range db.readreplicas {
+		db.applyPool(db.readreplicas[i])
+	}
+}
+
+// SetNodePool overrides the connection pool of d, which is master or one of readreplicas.
+func (db *DB) SetNodePool(d *sql.DB, p PoolConfig) {
+	db.logConfig("NodePool", p)
+
+	db.lk.Lock()
+	defer db.lk.Unlock()
+
+	db.nodePools[d] = db.nodePools[d].merge(p)
+	db.applyPool(d)
+}
+
+// applyPool applies the pool configuration of the role of d merged with its override.
+// db.lk must be held.
+func (db *DB) applyPool(d *sql.DB) {
+	if d == nil {
+		return
+	}
+
+	p := db.replicaPool
+	if d == db.master {
+		p = db.masterPool
+	}
+	p.merge(db.nodePools[d]).apply(d)
+}
@@ -0,0 +1,69 @@
+package mydb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPoolConfigMerge(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		c := PoolConfig{MaxOpenConns: 10, MaxIdleConns: 5, ConnMaxLifetime: time.Minute}
+		got := c.merge(PoolConfig{MaxIdleConns: -1, ConnMaxIdleTime: time.Second})
+
+		want := PoolConfig{MaxOpenConns: 10, MaxIdleConns: -1, ConnMaxLifetime: time.Minute, ConnMaxIdleTime: time.Second}
+		if got != want {
+			t.Errorf("merge() want %+v, but get %+v", want, got)
+		}
+	})
+}
+
+func TestPool(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica0, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		db := New(master, readreplica0, readreplica1)
+		defer db.Close()
+
+		db.SetMasterPool(PoolConfig{MaxOpenConns: 100, ConnMaxIdleTime: time.Minute})
+		db.SetReplicaPool(PoolConfig{MaxOpenConns: 30})
+		db.SetNodePool(readreplica1, PoolConfig{MaxOpenConns: 10})
+		// the override of readreplica1 takes precedence
+		db.SetReplicaPool(PoolConfig{MaxOpenConns: 40, MaxIdleConns: 5})
+
+		if got := master.Stats().MaxOpenConnections; got != 100 {
+			t.Errorf("master MaxOpenConnections want %d, but get %d", 100, got)
+		}
+		if got := readreplica0.Stats().MaxOpenConnections; got != 40 {
+			t.Errorf("readreplica0 MaxOpenConnections want %d, but get %d", 40, got)
+		}
+		if got := readreplica1.Stats().MaxOpenConnections; got != 10 {
+			t.Errorf("readreplica1 MaxOpenConnections want %d, but get %d", 10, got)
+		}
+
+		if got := db.GetMasterPool(); got != (PoolConfig{MaxOpenConns: 100, ConnMaxIdleTime: time.Minute}) {
+			t.Errorf("GetMasterPool() unexpected %+v", got)
+		}
+		if got := db.GetReplicaPool(); got != (PoolConfig{MaxOpenConns: 40, MaxIdleConns: 5}) {
+			t.Errorf("GetReplicaPool() unexpected %+v", got)
+		}
+
+		// a negative value removes the limit
+		db.SetMasterPool(PoolConfig{MaxOpenConns: -1})
+		if got := master.Stats().MaxOpenConnections; got != 0 {
+			t.Errorf("master MaxOpenConnections want %d, but get %d", 0, got)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package mydb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// NodeResult is the result of a query on a node.
+type NodeResult struct {
+	Name string
+	Role Role
+	Rows *sql.Rows
+	Err  error
+}
+
+// QueryAll runs query on master and every readreplica concurrently, regardless of their health,
+// e.g. to compare SELECT COUNT(*) or @@gtid_executed across the cluster.
+// Results are ordered master first, then readreplicas. The caller must close Rows of each result.
+// A node without a db, e.g. a nil master, has ErrNilDB.
+func (db *DB) QueryAll(ctx context.Context, query string, args ...interface{}) []NodeResult {
+	nodes := db.allNodes()
+	results := make([]NodeResult, len(nodes))
+	goFuncs(len(nodes), func(i int) error {
+		if nodes[i].db == nil {
+			results[i] = NodeResult{Name: nodes[i].Name(), Role: nodes[i].Role(), Err: ErrNilDB}
+			return ErrNilDB
+		}
+		rows, err := nodes[i].db.QueryContext(ctx, query, args...)
+		results[i] = NodeResult{
+			Name: nodes[i].Name(),
+			Role: nodes[i].Role(),
+			Rows: rows,
+			Err:  err,
+		}
+		return err
+	})
+
+	return results
+}
+
+// allNodes returns master and readreplicas.
+func (db *DB) allNodes() []*node {
+	return append([]*node{db.masterNode}, db.readDbBalancer.nodeList()...)
+}
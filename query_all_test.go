@@ -0,0 +1,99 @@
+package mydb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestQueryAll(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica0, readreplica0Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1, readreplica1Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		masterMock.ExpectQuery("select count").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+		readreplica0Mock.ExpectQuery("select count").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(9))
+		readreplica1Mock.ExpectQuery("select count").
+			WillReturnError(errors.New("query error"))
+
+		db := New(master, readreplica0, readreplica1)
+		defer db.Close()
+
+		results := db.QueryAll(context.Background(), "select count(*) from code")
+		if len(results) != 3 {
+			t.Fatalf("QueryAll() want %d results, but get %d", 3, len(results))
+		}
+
+		want := []struct {
+			name  string
+			role  Role
+			count int
+		}{
+			{name: "master", role: Master, count: 10},
+			{name: "readreplica0", role: ReadReplica, count: 9},
+		}
+		for i := range want {
+			if results[i].Name != want[i].name || results[i].Role != want[i].role {
+				t.Errorf("QueryAll() want %s, but get %s", want[i].name, results[i].Name)
+			}
+			if results[i].Err != nil {
+				t.Error(results[i].Err)
+				continue
+			}
+
+			var count int
+			results[i].Rows.Next()
+			if err := results[i].Rows.Scan(&count); err != nil {
+				t.Error(err)
+			}
+			results[i].Rows.Close()
+			if count != want[i].count {
+				t.Errorf("count of %s want %d, but get %d", want[i].name, want[i].count, count)
+			}
+		}
+
+		if results[2].Name != "readreplica1" || results[2].Err == nil {
+			t.Error("QueryAll() want error of readreplica1")
+		}
+
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+		if err := readreplica0Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+		if err := readreplica1Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+	t.Run("error with nil master", func(t *testing.T) {
+		readreplica, readreplicaMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplicaMock.ExpectQuery("select count").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(9))
+
+		db := New(nil, readreplica)
+		defer db.Close()
+
+		results := db.QueryAll(context.Background(), "select count(*) from code")
+		if len(results) != 2 || results[0].Err != ErrNilDB || results[1].Err != nil {
+			t.Fatalf("QueryAll() want %s of master, but get %+v", ErrNilDB, results)
+		}
+		results[1].Rows.Close()
+	})
+}
@@ -0,0 +1,166 @@
+package mydb
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRetryBudgetRatio = 0.1
+	// retryBudgetMaxTokens is the maximum number of retries which can be made in a burst.
+	retryBudgetMaxTokens = 10
+)
+
+var _ interface {
+	Deposit()
+	Withdraw() bool
+	Refund()
+	GetRatio() float64
+	SetRatio(ratio float64)
+} = newRetryBudget(DefaultRetryBudgetRatio)
+
+// retryBudget limits retries to a ratio of requests.
+// Each request deposits ratio tokens and each retry withdraws one token,
+// so that retries add at most ratio extra load even when every request fails.
+type retryBudget struct {
+	lk     sync.Mutex
+	ratio  float64
+	tokens float64
+}
+
+func newRetryBudget(ratio float64) *retryBudget {
+	return &retryBudget{
+		ratio:  ratio,
+		tokens: retryBudgetMaxTokens,
+	}
+}
+
+func (b *retryBudget) Deposit() {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	b.tokens += b.ratio
+	if b.tokens > retryBudgetMaxTokens {
+		b.tokens = retryBudgetMaxTokens
+	}
+}
+
+func (b *retryBudget) Withdraw() bool {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	if b.ratio <= 0 || b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Refund gives back a token withdrawn for a retry which was not made.
+func (b *retryBudget) Refund() {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	b.tokens++
+	if b.tokens > retryBudgetMaxTokens {
+		b.tokens = retryBudgetMaxTokens
+	}
+}
+
+func (b *retryBudget) GetRatio() float64 {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	return b.ratio
+}
+
+func (b *retryBudget) SetRatio(ratio float64) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	b.ratio = ratio
+}
+
+// read runs fn on a readreplica, hedged if configured, and returns the result with
+// the node which served it. If fn fails with a connection error, it is retried on
+// another readreplica and then on master according to FallbackType,
+// as long as the retry budget allows.
+func (db *DB) read(ctx context.Context, fn readFunc) (interface{}, *node, error) {
+	d, err := db.getReadReplica(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	db.retryBudget.Deposit()
+
+	res, d, err := db.hedge(ctx, d, fn)
+
+	tried := make([]*sql.DB, 0, 1)
+	retries := 0
+	for {
+		if !isConnError(err) || ctx.Err() != nil {
+			break
+		}
+
+		// withdraw before taking a half-open trial, which must not be left unused,
+		// and give the token back if the trial is not taken
+		tried = append(tried, d)
+		next := db.retryTarget(tried)
+		if next == nil || !db.retryBudget.Withdraw() {
+			break
+		}
+		if !db.allow(next) {
+			db.retryBudget.Refund()
+			break
+		}
+		d = next
+		retries++
+		db.metrics.observeRetry()
+
+		done := db.track(d, opQuery)
+		res, err = fn(ctx, d)
+		done(err)
+	}
+
+	n := db.nodeOf(d)
+	traceNode(ctx, n)
+	if retries > 0 {
+		setSpanAttribute(ctx, "mydb.retries", retries)
+	}
+	return res, n, err
+}
+
+// nodeOf returns the node of d, or nil if d is not owned by db.
+func (db *DB) nodeOf(d *sql.DB) *node {
+	if d == db.master {
+		return db.masterNode
+	}
+	return db.readDbBalancer.nodes[d]
+}
+
+// retryTarget returns a db which is not tried yet, or nil.
+// It does not take the trial of a half-open breaker.
+func (db *DB) retryTarget(tried []*sql.DB) *sql.DB {
+	if d := db.readDbBalancer.GetExcluding(tried); d != nil {
+		return d
+	}
+
+	if db.fallbackType != UseMaster {
+		return nil
+	}
+	for i := range tried {
+		if tried[i] == db.master {
+			return nil
+		}
+	}
+	if db.masterHealth != nil || !db.masterNode.breaker.admits(db.GetCircuitBreaker(), time.Now()) {
+		return nil
+	}
+	return db.master
+}
+
+// allow takes a call of the circuit breaker of d, which counts as a trial if it is half-open.
+func (db *DB) allow(d *sql.DB) bool {
+	return db.nodeOf(d).breaker.allow(db.GetCircuitBreaker(), time.Now())
+}
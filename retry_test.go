@@ -0,0 +1,259 @@
+package mydb
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRetryBudget(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		b := newRetryBudget(0.5)
+		for i := 0; i < retryBudgetMaxTokens; i++ {
+			if !b.Withdraw() {
+				t.Errorf("Withdraw() want true at %d", i)
+			}
+		}
+		if b.Withdraw() {
+			t.Error("Withdraw() want false with empty budget")
+		}
+
+		b.Deposit()
+		b.Deposit()
+		if !b.Withdraw() {
+			t.Error("Withdraw() want true after 2 deposits")
+		}
+		if b.Withdraw() {
+			t.Error("Withdraw() want false with empty budget")
+		}
+	})
+
+	t.Run("success with refund", func(t *testing.T) {
+		b := newRetryBudget(0.5)
+		b.Withdraw()
+		b.Refund()
+		b.Refund()
+		if b.tokens != retryBudgetMaxTokens {
+			t.Errorf("tokens want %d, but get %v", retryBudgetMaxTokens, b.tokens)
+		}
+	})
+
+	t.Run("success with disabled", func(t *testing.T) {
+		b := newRetryBudget(0)
+		if b.Withdraw() {
+			t.Error("Withdraw() want false")
+		}
+	})
+}
+
+func TestRetry(t *testing.T) {
+	t.Run("success with other readreplica", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica0, readreplica0Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1, readreplica1Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1Mock.ExpectQuery("select 1").
+			WillReturnError(errors.New("invalid connection"))
+		readreplica0Mock.ExpectQuery("select 1").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+
+		db := New(master, readreplica0, readreplica1)
+		defer db.Close()
+		db.SetBalanceAlgorithm(RoundRobin)
+
+		if _, err = db.Query("select 1"); err != nil {
+			t.Error(err)
+		}
+
+		if err := readreplica0Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+		if err := readreplica1Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("success with master", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, readreplicaMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplicaMock.ExpectQuery("select 1").
+			WillReturnError(errors.New("invalid connection"))
+		masterMock.ExpectQuery("select 1").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		db := New(master, readreplica)
+		defer db.Close()
+
+		var id int
+		if err = db.QueryRow("select 1").Scan(&id); err != nil {
+			t.Error(err)
+		}
+
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+		if err := readreplicaMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("error with fallback none", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, readreplicaMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplicaMock.ExpectQuery("select 1").
+			WillReturnError(errors.New("invalid connection"))
+
+		db := New(master, readreplica)
+		defer db.Close()
+		db.SetFallbackType(None)
+
+		if _, err = db.Query("select 1"); err == nil {
+			t.Error("Query() want error")
+		}
+
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("error with statement error", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica0, readreplica0Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1, readreplica1Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1Mock.ExpectQuery("select 1").
+			WillReturnError(errors.New("Error 1064: syntax error"))
+
+		db := New(master, readreplica0, readreplica1)
+		defer db.Close()
+		db.SetBalanceAlgorithm(RoundRobin)
+
+		if _, err = db.Query("select 1"); err == nil {
+			t.Error("Query() want error")
+		}
+
+		if err := readreplica0Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+	t.Run("success without retry target", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, readreplicaMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		for i := 0; i < 20; i++ {
+			readreplicaMock.ExpectQuery("select 1").
+				WillReturnError(errors.New("invalid connection"))
+		}
+
+		db := New(master, readreplica)
+		defer db.Close()
+		db.SetFallbackType(None)
+
+		for i := 0; i < 20; i++ {
+			if _, err = db.Query("select 1"); err == nil {
+				t.Error("Query() want error")
+			}
+		}
+		if db.retryBudget.tokens != retryBudgetMaxTokens {
+			t.Errorf("tokens want %d, but get %v", retryBudgetMaxTokens, db.retryBudget.tokens)
+		}
+		if db.Stats().Retries != 0 {
+			t.Errorf("Retries want 0, but get %d", db.Stats().Retries)
+		}
+	})
+
+	t.Run("success with half-open readreplica and empty budget", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica0, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1, readreplica1Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1Mock.ExpectQuery("select 1").
+			WillReturnError(errors.New("invalid connection"))
+
+		db := New(master, readreplica0, readreplica1)
+		defer db.Close()
+		db.SetBalanceAlgorithm(RoundRobin)
+		db.SetRetryBudgetRatio(0)
+		c := CircuitBreaker{FailureThreshold: 2}
+		db.SetCircuitBreaker(c)
+
+		b := &db.readDbBalancer.nodes[readreplica0].breaker
+		b.lk.Lock()
+		b.state = BreakerHalfOpen
+		b.lk.Unlock()
+
+		if _, err = db.Query("select 1"); err == nil {
+			t.Error("Query() want error")
+		}
+		if !b.admits(c, time.Now()) {
+			t.Error("admits() want true, since the trial of readreplica0 is not taken")
+		}
+
+		if err := readreplica1Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
+
+func TestRetryBudgetRatio(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		db := New(master)
+		defer db.Close()
+
+		if db.GetRetryBudgetRatio() != DefaultRetryBudgetRatio {
+			t.Errorf("GetRetryBudgetRatio() want %f", DefaultRetryBudgetRatio)
+		}
+
+		db.SetRetryBudgetRatio(0.2)
+		if db.GetRetryBudgetRatio() != 0.2 {
+			t.Error("GetRetryBudgetRatio() want 0.2")
+		}
+	})
+}
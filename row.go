@@ -0,0 +1,38 @@
+package mydb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+)
+
+// errConnector fails every connection with err.
+type errConnector struct {
+	err error
+}
+
+func (c errConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return nil, c.err
+}
+
+func (c errConnector) Driver() driver.Driver {
+	return errDriver(c)
+}
+
+type errDriver struct {
+	err error
+}
+
+func (d errDriver) Open(name string) (driver.Conn, error) {
+	return nil, d.err
+}
+
+// errRow returns a *sql.Row whose Scan and Err return err.
+// *sql.Row has no exported constructor, so the row is made by a query
+// on a db whose connections fail with err.
+func errRow(err error) *sql.Row {
+	db := sql.OpenDB(errConnector{err: err})
+	defer db.Close()
+
+	return db.QueryRow("")
+}
@@ -0,0 +1,21 @@
+package mydb
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrRow(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		rowErr := errors.New("row error")
+		row := errRow(rowErr)
+
+		if err := row.Err(); err != rowErr {
+			t.Errorf("Err() want %s, but get %v", rowErr, err)
+		}
+		var v int
+		if err := row.Scan(&v); err != rowErr {
+			t.Errorf("Scan() want %s, but get %v", rowErr, err)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package mydb
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pkgPrefix is the prefix of the function names of this package, e.g. "mydb.".
+var pkgPrefix = reflect.TypeOf((*DB)(nil)).Elem().PkgPath() + "."
+
+// logSlowQuery logs call if it took longer than the slow query threshold.
+func (db *DB) logSlowQuery(call *Call, elapsed time.Duration) {
+	threshold := db.GetSlowQueryThreshold()
+	if threshold <= 0 || elapsed < threshold {
+		return
+	}
+
+	db.GetLogger().Warn("slow query",
+		"duration", elapsed,
+		"method", call.Method,
+		"node", call.Node,
+		"role", call.Role.String(),
+		"query", normalizeSQL(call.Query),
+		"args", redactArgs(call.Args),
+		"caller", caller(),
+	)
+}
+
+// caller returns file:line of the first caller outside this package.
+func caller() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, pkgPrefix) || strings.HasSuffix(f.File, "_test.go") {
+			return filepath.Base(f.File) + ":" + strconv.Itoa(f.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// redactArgs describes args by their types and lengths without their values,
+// e.g. [int string(5) []uint8(16) nil].
+func redactArgs(args []interface{}) string {
+	res := make([]string, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case nil:
+			res[i] = "nil"
+		case string:
+			res[i] = fmt.Sprintf("string(%d)", len(v))
+		case []byte:
+			res[i] = fmt.Sprintf("[]uint8(%d)", len(v))
+		default:
+			res[i] = fmt.Sprintf("%T", arg)
+		}
+	}
+	return "[" + strings.Join(res, " ") + "]"
+}
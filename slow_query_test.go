@@ -0,0 +1,93 @@
+package mydb
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRedactArgs(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		got := redactArgs([]interface{}{1, "secret", []byte("abc"), nil, time.Time{}})
+		want := "[int string(6) []uint8(3) nil time.Time]"
+		if got != want {
+			t.Errorf("redactArgs() want %s, but get %s", want, got)
+		}
+	})
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, readreplicaMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplicaMock.ExpectQuery("SELECT \\* FROM code").
+			WithArgs("secret").
+			WillDelayFor(20 * time.Millisecond).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		readreplicaMock.ExpectQuery("SELECT 1").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+
+		db := New(master, readreplica)
+		defer db.Close()
+
+		l := &testLogger{}
+		db.SetLogger(l)
+		db.SetSlowQueryThreshold(10 * time.Millisecond)
+
+		rows, err := db.Query("SELECT * FROM code WHERE name = 'a' AND id = ?", "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+		rows, err = db.Query("SELECT 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+
+		var slow []testLogEntry
+		for _, e := range l.entries {
+			if e.msg == "slow query" {
+				slow = append(slow, e)
+			}
+		}
+		if len(slow) != 1 {
+			t.Fatalf("slow queries want %d, but get %+v", 1, slow)
+		}
+
+		kv := make(map[string]interface{})
+		for i := 0; i+1 < len(slow[0].keyvals); i += 2 {
+			kv[slow[0].keyvals[i].(string)] = slow[0].keyvals[i+1]
+		}
+		if d, _ := kv["duration"].(time.Duration); d < 10*time.Millisecond {
+			t.Errorf("duration want >= %s, but get %v", 10*time.Millisecond, kv["duration"])
+		}
+		want := map[string]interface{}{
+			"method": "Query",
+			"node":   "readreplica0",
+			"role":   "readreplica",
+			"query":  "SELECT * FROM code WHERE name = ? AND id = ?",
+			"args":   "[string(6)]",
+		}
+		for k, v := range want {
+			if kv[k] != v {
+				t.Errorf("%s want %v, but get %v", k, v, kv[k])
+			}
+		}
+		if c, _ := kv["caller"].(string); !strings.HasPrefix(c, "slow_query_test.go:") {
+			t.Errorf("caller want slow_query_test.go:*, but get %v", kv["caller"])
+		}
+
+		if err := readreplicaMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
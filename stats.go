@@ -0,0 +1,59 @@
+package mydb
+
+import "database/sql"
+
+// Stats are the connection pool stats and the routing counters of db.
+type Stats struct {
+	Master sql.DBStats
+	// Readreplicas are the stats of readreplicas in the order given to New.
+	Readreplicas []sql.DBStats
+	// ReadreplicasTotal is the sum of Readreplicas.
+	ReadreplicasTotal sql.DBStats
+
+	// Reads and Writes are the routed calls. Reads are Query and QueryRow,
+	// and writes are Exec, Prepare and BeginTx.
+	Reads  int64
+	Writes int64
+	// Fallbacks are the reads routed to master because no readreplica was available.
+	Fallbacks int64
+	// Retries are the reads retried on another node after a connection error.
+	Retries int64
+	// Ejections are the readreplicas ejected by outlier detection.
+	Ejections int64
+}
+
+// Stats returns the connection pool stats and the routing counters.
+func (db *DB) Stats() Stats {
+	s := Stats{
+		Readreplicas: make([]sql.DBStats, len(db.readreplicas)),
+		Reads:        db.metrics.Reads(),
+		Writes:       db.metrics.Writes(),
+		Fallbacks:    db.metrics.Fallbacks(),
+		Retries:      db.metrics.Retries(),
+		Ejections:    db.readDbBalancer.Ejections(),
+	}
+
+	if db.master != nil {
+		s.Master = db.master.Stats()
+	}
+	for i := range db.readreplicas {
+		s.Readreplicas[i] = db.readreplicas[i].Stats()
+		s.ReadreplicasTotal = addDBStats(s.ReadreplicasTotal, s.Readreplicas[i])
+	}
+
+	return s
+}
+
+func addDBStats(a, b sql.DBStats) sql.DBStats {
+	return sql.DBStats{
+		MaxOpenConnections: a.MaxOpenConnections + b.MaxOpenConnections,
+		OpenConnections:    a.OpenConnections + b.OpenConnections,
+		InUse:              a.InUse + b.InUse,
+		Idle:               a.Idle + b.Idle,
+		WaitCount:          a.WaitCount + b.WaitCount,
+		WaitDuration:       a.WaitDuration + b.WaitDuration,
+		MaxIdleClosed:      a.MaxIdleClosed + b.MaxIdleClosed,
+		MaxIdleTimeClosed:  a.MaxIdleTimeClosed + b.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  a.MaxLifetimeClosed + b.MaxLifetimeClosed,
+	}
+}
@@ -0,0 +1,105 @@
+package mydb
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestStats(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica0, readreplica0Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1, readreplica1Mock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica1Mock.ExpectQuery("select 1").
+			WillReturnError(errors.New("invalid connection"))
+		readreplica0Mock.ExpectQuery("select 1").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+		masterMock.ExpectExec("insert into code").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		masterMock.ExpectQuery("select 1").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+
+		db := New(master, readreplica0, readreplica1)
+		defer db.Close()
+		db.SetBalanceAlgorithm(RoundRobin)
+		db.SetOutlierDetection(OutlierDetection{ConsecutiveErrors: 1})
+		db.SetMaxOpenConns(5)
+
+		// readreplica1 loses connection, is ejected and the read is retried on readreplica0
+		rows, err := db.Query("select 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+		if _, err := db.Exec("insert into code values (1)"); err != nil {
+			t.Fatal(err)
+		}
+		// all readreplicas died, reads fall back to master
+		db.readDbBalancer.availableDbs.Replace(nil)
+		rows, err = db.Query("select 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+
+		s := db.Stats()
+		counters := map[string][2]int64{
+			"Reads":     {2, s.Reads},
+			"Writes":    {1, s.Writes},
+			"Fallbacks": {1, s.Fallbacks},
+			"Retries":   {1, s.Retries},
+			"Ejections": {1, s.Ejections},
+		}
+		for name, c := range counters {
+			if c[0] != c[1] {
+				t.Errorf("%s want %d, but get %d", name, c[0], c[1])
+			}
+		}
+
+		if s.Master.MaxOpenConnections != 5 {
+			t.Errorf("Master.MaxOpenConnections want %d, but get %d", 5, s.Master.MaxOpenConnections)
+		}
+		if len(s.Readreplicas) != 2 {
+			t.Fatalf("Readreplicas want %d, but get %d", 2, len(s.Readreplicas))
+		}
+		if s.ReadreplicasTotal.MaxOpenConnections != 10 {
+			t.Errorf("ReadreplicasTotal.MaxOpenConnections want %d, but get %d", 10, s.ReadreplicasTotal.MaxOpenConnections)
+		}
+
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+		if err := readreplica0Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+		if err := readreplica1Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
+
+func TestAddDBStats(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		a := sql.DBStats{OpenConnections: 1, InUse: 1, WaitCount: 2, WaitDuration: time.Second}
+		b := sql.DBStats{OpenConnections: 2, Idle: 2, WaitCount: 3, WaitDuration: time.Second}
+
+		got := addDBStats(a, b)
+		want := sql.DBStats{OpenConnections: 3, InUse: 1, Idle: 2, WaitCount: 5, WaitDuration: 2 * time.Second}
+		if got != want {
+			t.Errorf("addDBStats() want %+v, but get %+v", want, got)
+		}
+	})
+}
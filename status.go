@@ -0,0 +1,82 @@
+package mydb
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Status is the state of the cluster.
+type Status struct {
+	// Nodes are the statuses of master and readreplicas, master first.
+	Nodes []NodeStatus
+	// Fallback is true if no readreplica is available,
+	// so that reads go to master or fail depending on the fallback type.
+	Fallback bool
+}
+
+// NodeStatus is the state of a node.
+type NodeStatus struct {
+	Name string
+	Role Role
+	Zone string
+	// Available is true if the node is used for routing.
+	Available bool
+	Healthy   bool
+	Ejected   bool
+	Breaker   BreakerState
+	// LastProbe is the start time of the last health check. Zero if never checked.
+	LastProbe    time.Time
+	ProbeLatency time.Duration
+	// LastError is the error of the last failed health check.
+	LastError           error
+	ConsecutiveFailures int
+	// Lag is the replication lag. HasLag is false if the health checker
+	// does not implement LagChecker or the last measurement failed.
+	Lag      time.Duration
+	HasLag   bool
+	Inflight int64
+	Latency  time.Duration
+	Stats    sql.DBStats
+}
+
+func (n *node) status(c CircuitBreaker, now time.Time) NodeStatus {
+	breakerState := n.breaker.State(c, now)
+	ejected := n.isEjected(now)
+
+	var stats sql.DBStats
+	if n.db != nil {
+		stats = n.db.Stats()
+	}
+
+	n.lk.RLock()
+	defer n.lk.RUnlock()
+
+	return NodeStatus{
+		Name:                n.name,
+		Role:                n.role,
+		Zone:                n.zone,
+		Healthy:             n.healthy,
+		Ejected:             ejected,
+		Breaker:             breakerState,
+		LastProbe:           n.lastProbe,
+		ProbeLatency:        n.probeLatency,
+		LastError:           n.healthErr,
+		ConsecutiveFailures: n.consecutiveFailures,
+		Lag:                 n.lag,
+		HasLag:              n.hasLag,
+		Inflight:            n.Inflight(),
+		Latency:             n.Latency(),
+		Stats:               stats,
+	}
+}
+
+// Status returns the state of master and readreplicas.
+func (db *DB) Status() Status {
+	master := db.masterNode.status(db.GetCircuitBreaker(), time.Now())
+	master.Available = master.Healthy && master.Breaker != BreakerOpen
+
+	return Status{
+		Nodes:    append([]NodeStatus{master}, db.readDbBalancer.Statuses()...),
+		Fallback: !db.readDbBalancer.IsAlive(),
+	}
+}
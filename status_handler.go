@@ -0,0 +1,170 @@
+package mydb
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Probe decides readiness or liveness from the cluster status. A non-nil error fails the probe.
+type Probe func(s Status) error
+
+// MasterUp passes if master is available. It is the default ready probe.
+func MasterUp() Probe {
+	return func(s Status) error {
+		for i := range s.Nodes {
+			if s.Nodes[i].Role == Master && s.Nodes[i].Available {
+				return nil
+			}
+		}
+		return ErrMasterDied
+	}
+}
+
+// ReplicasUp passes if at least min readreplicas are available.
+func ReplicasUp(min int) Probe {
+	return func(s Status) error {
+		var n int
+		for i := range s.Nodes {
+			if s.Nodes[i].Role == ReadReplica && s.Nodes[i].Available {
+				n++
+			}
+		}
+		if n < min {
+			return ErrTooFewReadreplicas
+		}
+		return nil
+	}
+}
+
+// AlwaysUp always passes. It is the default live probe,
+// since a process which can serve the probe is alive.
+func AlwaysUp() Probe {
+	return func(s Status) error {
+		return nil
+	}
+}
+
+// AllProbes composes probes. All of them must pass.
+func AllProbes(probes ...Probe) Probe {
+	return func(s Status) error {
+		for i := range probes {
+			if err := probes[i](s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// StatusHandler returns an http.Handler serving the cluster status.
+// A path ending with /ready or /live runs the ready or live probe,
+// and responds 200 if it passes, otherwise 503.
+// Any other path responds the status as JSON.
+func StatusHandler(db *DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/ready"):
+			serveProbe(w, db.GetReadyProbe(), db.Status())
+		case strings.HasSuffix(r.URL.Path, "/live"):
+			serveProbe(w, db.GetLiveProbe(), db.Status())
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(newJSONStatus(db.Status()))
+		}
+	})
+}
+
+func serveProbe(w http.ResponseWriter, p Probe, s Status) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := p(s); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(err.Error() + "\n"))
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+
+type jsonStatus struct {
+	Fallback bool             `json:"fallback"`
+	Nodes    []jsonNodeStatus `json:"nodes"`
+}
+
+type jsonNodeStatus struct {
+	Name                string     `json:"name"`
+	Role                string     `json:"role"`
+	Zone                string     `json:"zone,omitempty"`
+	Available           bool       `json:"available"`
+	Healthy             bool       `json:"healthy"`
+	Ejected             bool       `json:"ejected"`
+	Breaker             string     `json:"breaker"`
+	LastProbe           *time.Time `json:"last_probe,omitempty"`
+	ProbeLatencySeconds float64    `json:"probe_latency_seconds"`
+	LastError           string     `json:"last_error,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LagSeconds          *float64   `json:"lag_seconds,omitempty"`
+	Inflight            int64      `json:"inflight"`
+	LatencySeconds      float64    `json:"latency_seconds"`
+	Pool                jsonPool   `json:"pool"`
+}
+
+type jsonPool struct {
+	MaxOpenConnections int     `json:"max_open_connections"`
+	OpenConnections    int     `json:"open_connections"`
+	InUse              int     `json:"in_use"`
+	Idle               int     `json:"idle"`
+	WaitCount          int64   `json:"wait_count"`
+	WaitSeconds        float64 `json:"wait_seconds"`
+	MaxIdleClosed      int64   `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64   `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64   `json:"max_lifetime_closed"`
+}
+
+func newJSONStatus(s Status) jsonStatus {
+	res := jsonStatus{
+		Fallback: s.Fallback,
+		Nodes:    make([]jsonNodeStatus, len(s.Nodes)),
+	}
+
+	for i, n := range s.Nodes {
+		j := jsonNodeStatus{
+			Name:                n.Name,
+			Role:                n.Role.String(),
+			Zone:                n.Zone,
+			Available:           n.Available,
+			Healthy:             n.Healthy,
+			Ejected:             n.Ejected,
+			Breaker:             n.Breaker.String(),
+			ProbeLatencySeconds: n.ProbeLatency.Seconds(),
+			ConsecutiveFailures: n.ConsecutiveFailures,
+			Inflight:            n.Inflight,
+			LatencySeconds:      n.Latency.Seconds(),
+			Pool: jsonPool{
+				MaxOpenConnections: n.Stats.MaxOpenConnections,
+				OpenConnections:    n.Stats.OpenConnections,
+				InUse:              n.Stats.InUse,
+				Idle:               n.Stats.Idle,
+				WaitCount:          n.Stats.WaitCount,
+				WaitSeconds:        n.Stats.WaitDuration.Seconds(),
+				MaxIdleClosed:      n.Stats.MaxIdleClosed,
+				MaxIdleTimeClosed:  n.Stats.MaxIdleTimeClosed,
+				MaxLifetimeClosed:  n.Stats.MaxLifetimeClosed,
+			},
+		}
+		if !n.LastProbe.IsZero() {
+			lastProbe := n.LastProbe
+			j.LastProbe = &lastProbe
+		}
+		if n.LastError != nil {
+			j.LastError = n.LastError.Error()
+		}
+		if n.HasLag {
+			lag := n.Lag.Seconds()
+			j.LagSeconds = &lag
+		}
+		res.Nodes[i] = j
+	}
+
+	return res
+}
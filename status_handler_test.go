@@ -0,0 +1,129 @@
+package mydb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestProbes(t *testing.T) {
+	status := Status{Nodes: []NodeStatus{
+		{Name: "master", Role: Master, Available: true},
+		{Name: "readreplica0", Role: ReadReplica, Available: true},
+		{Name: "readreplica1", Role: ReadReplica},
+	}}
+
+	t.Run("success", func(t *testing.T) {
+		if err := AllProbes(MasterUp(), ReplicasUp(1), AlwaysUp())(status); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("error with too few readreplicas", func(t *testing.T) {
+		if err := ReplicasUp(2)(status); err != ErrTooFewReadreplicas {
+			t.Errorf("ReplicasUp() want %s, but get %v", ErrTooFewReadreplicas, err)
+		}
+	})
+
+	t.Run("error with master died", func(t *testing.T) {
+		status := Status{Nodes: []NodeStatus{{Name: "master", Role: Master}}}
+		if err := AllProbes(AlwaysUp(), MasterUp())(status); err != ErrMasterDied {
+			t.Errorf("MasterUp() want %s, but get %v", ErrMasterDied, err)
+		}
+	})
+}
+
+func TestStatusHandler(t *testing.T) {
+	newDB := func(t *testing.T) *DB {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		return New(master, readreplica)
+	}
+	get := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	t.Run("success", func(t *testing.T) {
+		db := newDB(t)
+		defer db.Close()
+
+		w := get(StatusHandler(db), "/status")
+		if w.Code != http.StatusOK {
+			t.Errorf("status code want %d, but get %d", http.StatusOK, w.Code)
+		}
+
+		var res jsonStatus
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Fallback || len(res.Nodes) != 2 {
+			t.Fatalf("unexpected status %+v", res)
+		}
+		if res.Nodes[0].Role != "master" || res.Nodes[1].Name != "readreplica0" || !res.Nodes[1].Available {
+			t.Errorf("unexpected nodes %+v", res.Nodes)
+		}
+	})
+
+	t.Run("success with ready and live", func(t *testing.T) {
+		db := newDB(t)
+		defer db.Close()
+
+		h := StatusHandler(db)
+		for _, path := range []string{"/ready", "/healthz/live"} {
+			if w := get(h, path); w.Code != http.StatusOK {
+				t.Errorf("%s status code want %d, but get %d", path, http.StatusOK, w.Code)
+			}
+		}
+	})
+
+	t.Run("error with ready probe", func(t *testing.T) {
+		db := newDB(t)
+		defer db.Close()
+
+		db.SetReplicaHealthChecker(HealthCheckerFunc(func(ctx context.Context, db *sql.DB) error {
+			return errors.New("check error")
+		}))
+		db.readDbBalancer.healthCheck()
+		db.SetReadyProbe(AllProbes(MasterUp(), ReplicasUp(1)))
+
+		h := StatusHandler(db)
+		w := get(h, "/ready")
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("status code want %d, but get %d", http.StatusServiceUnavailable, w.Code)
+		}
+		if w.Body.String() != ErrTooFewReadreplicas.Error()+"\n" {
+			t.Errorf("body want %q, but get %q", ErrTooFewReadreplicas.Error()+"\n", w.Body.String())
+		}
+		if w := get(h, "/live"); w.Code != http.StatusOK {
+			t.Errorf("status code want %d, but get %d", http.StatusOK, w.Code)
+		}
+	})
+	t.Run("success with nil probes", func(t *testing.T) {
+		db := newDB(t)
+		defer db.Close()
+
+		db.SetReadyProbe(nil)
+		db.SetLiveProbe(nil)
+
+		h := StatusHandler(db)
+		for _, path := range []string{"/ready", "/live"} {
+			if w := get(h, path); w.Code != http.StatusOK {
+				t.Errorf("%s status code want %d, but get %d", path, http.StatusOK, w.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package mydb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestStatus(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		db := New(master, readreplica)
+		defer db.Close()
+
+		status := db.Status()
+		if status.Fallback {
+			t.Error("Fallback want false")
+		}
+		if len(status.Nodes) != 2 {
+			t.Fatalf("Nodes want %d, but get %d", 2, len(status.Nodes))
+		}
+		for i, name := range []string{"master", "readreplica0"} {
+			n := status.Nodes[i]
+			if n.Name != name {
+				t.Errorf("Name want %s, but get %s", name, n.Name)
+			}
+			if !n.Available || !n.Healthy {
+				t.Errorf("%s want available and healthy", name)
+			}
+			if n.LastProbe.IsZero() {
+				t.Errorf("%s LastProbe want non zero", name)
+			}
+			if n.HasLag {
+				t.Errorf("%s HasLag want false", name)
+			}
+		}
+	})
+
+	t.Run("success with lag", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, readreplicaMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		db := New(master, readreplica)
+		defer db.Close()
+
+		columns := []string{"Replica_IO_Running", "Replica_SQL_Running", "Seconds_Behind_Source"}
+		readreplicaMock.ExpectQuery("SHOW REPLICA STATUS").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("Yes", "Yes", "3"))
+		readreplicaMock.ExpectQuery("SHOW REPLICA STATUS").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("Yes", "Yes", "3"))
+		db.SetReplicaHealthChecker(AllCheckers(PingChecker(), ReplicationChecker()))
+		db.readDbBalancer.healthCheck()
+
+		n := db.Status().Nodes[1]
+		if !n.HasLag || n.Lag != 3*time.Second {
+			t.Errorf("Lag want %s, but get %s (HasLag %t)", 3*time.Second, n.Lag, n.HasLag)
+		}
+
+		if err := readreplicaMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("success with fallback", func(t *testing.T) {
+		master, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+
+		db := New(master, readreplica)
+		defer db.Close()
+
+		checkErr := errors.New("check error")
+		db.SetReplicaHealthChecker(HealthCheckerFunc(func(ctx context.Context, db *sql.DB) error {
+			return checkErr
+		}))
+		db.readDbBalancer.healthCheck()
+
+		status := db.Status()
+		if !status.Fallback {
+			t.Error("Fallback want true")
+		}
+		n := status.Nodes[1]
+		if n.Available || n.Healthy {
+			t.Error("readreplica0 want unavailable and unhealthy")
+		}
+		if n.LastError != checkErr {
+			t.Errorf("LastError want %s, but get %v", checkErr, n.LastError)
+		}
+		if n.ConsecutiveFailures != 1 {
+			t.Errorf("ConsecutiveFailures want %d, but get %d", 1, n.ConsecutiveFailures)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package mydb
+
+import (
+	"context"
+)
+
+// Tracer starts spans. Adapt it to OpenTelemetry or any other tracing library.
+// The parent span is taken from ctx, and the returned ctx must carry the new span.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a traced call.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+type spanKeyType struct{}
+
+var spanKey = spanKeyType{}
+
+// startSpan starts the span of op if a tracer is set.
+func (db *DB) startSpan(ctx context.Context, op string, query string) (context.Context, Span) {
+	t := db.GetTracer()
+	if t == nil {
+		return ctx, nil
+	}
+
+	ctx, span := t.Start(ctx, "mydb."+op)
+	span.SetAttribute("db.system", "mysql")
+	span.SetAttribute("db.operation", op)
+	if query != "" {
+		span.SetAttribute("db.statement", normalizeSQL(query))
+	}
+
+	return context.WithValue(ctx, spanKey, span), span
+}
+
+// endSpan ends span with the call result. span may be nil.
+func endSpan(span Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+func spanFromContext(ctx context.Context) (Span, bool) {
+	span, ok := ctx.Value(spanKey).(Span)
+	return span, ok
+}
+
+func setSpanAttribute(ctx context.Context, key string, value interface{}) {
+	if span, ok := spanFromContext(ctx); ok {
+		span.SetAttribute(key, value)
+	}
+}
+
+// traceNode records the node which served the call.
+func traceNode(ctx context.Context, n *node) {
+	if n == nil {
+		return
+	}
+	if span, ok := spanFromContext(ctx); ok {
+		span.SetAttribute("mydb.node", n.Name())
+		span.SetAttribute("mydb.role", n.Role().String())
+	}
+}
@@ -0,0 +1,212 @@
+package mydb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type testSpan struct {
+	name       string
+	parent     *testSpan
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) {
+	s.attributes[key] = value
+}
+
+func (s *testSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *testSpan) End() {
+	s.ended = true
+}
+
+type testSpanKey struct{}
+
+type testTracer struct {
+	lk    sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	parent, _ := ctx.Value(testSpanKey{}).(*testSpan)
+	span := &testSpan{name: name, parent: parent, attributes: make(map[string]interface{})}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func TestTracing(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, readreplicaMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplicaMock.ExpectQuery("SELECT \\* FROM code").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		execErr := errors.New("Error 1062 (23000): Duplicate entry '1' for key 'PRIMARY'")
+		masterMock.ExpectExec("INSERT INTO code").
+			WillReturnError(execErr)
+
+		db := New(master, readreplica)
+		defer db.Close()
+		db.SetBalanceAlgorithm(P2C)
+		tracer := &testTracer{}
+		db.SetTracer(tracer)
+
+		rows, err := db.Query("SELECT * FROM code WHERE id = 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+		db.Exec("INSERT INTO code VALUES (1)")
+
+		if len(tracer.spans) != 2 {
+			t.Fatalf("spans want %d, but get %d", 2, len(tracer.spans))
+		}
+
+		query := tracer.spans[0]
+		want := map[string]interface{}{
+			"db.system":       "mysql",
+			"db.operation":    "query",
+			"db.statement":    "SELECT * FROM code WHERE id = ?",
+			"mydb.balancer":   "p2c",
+			"mydb.local_zone": false,
+			"mydb.node":       "readreplica0",
+			"mydb.role":       "readreplica",
+		}
+		for k, v := range want {
+			if query.attributes[k] != v {
+				t.Errorf("attribute %s want %v, but get %v", k, v, query.attributes[k])
+			}
+		}
+		if query.name != "mydb.query" || !query.ended || query.err != nil {
+			t.Errorf("unexpected query span %+v", query)
+		}
+
+		exec := tracer.spans[1]
+		if exec.name != "mydb.exec" || exec.attributes["mydb.node"] != "master" || exec.err != execErr || !exec.ended {
+			t.Errorf("unexpected exec span %+v", exec)
+		}
+
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+		if err := readreplicaMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("success with fallback", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		readreplica, _, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		masterMock.ExpectQuery("SELECT 1").
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+
+		db := New(master, readreplica)
+		defer db.Close()
+		tracer := &testTracer{}
+		db.SetTracer(tracer)
+
+		// all readreplicas died, reads fall back to master
+		db.readDbBalancer.availableDbs.Replace(nil)
+		var v int
+		if err := db.QueryRow("SELECT 1").Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		span := tracer.spans[0]
+		if span.attributes["mydb.fallback"] != true || span.attributes["mydb.node"] != "master" {
+			t.Errorf("unexpected span attributes %+v", span.attributes)
+		}
+	})
+
+	t.Run("success with traced transaction", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		masterMock.ExpectBegin()
+		masterMock.ExpectExec("INSERT INTO code").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		masterMock.ExpectCommit()
+
+		db := New(master)
+		defer db.Close()
+		tracer := &testTracer{}
+		db.SetTracer(tracer)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		tx, err := db.BeginTracedTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+		begin := tracer.spans[0]
+		if begin.name != "mydb.begin" || begin.ended {
+			t.Errorf("begin span want running until commit %+v", begin)
+		}
+
+		// a statement context without the transaction span
+		if _, err := tx.ExecContext(context.Background(), "INSERT INTO code VALUES (1)"); err != nil {
+			t.Fatal(err)
+		}
+		exec := tracer.spans[1]
+		if exec.name != "mydb.exec" || exec.attributes["mydb.node"] != "master" || !exec.ended {
+			t.Errorf("unexpected exec span %+v", exec)
+		}
+		if exec.parent != begin {
+			t.Errorf("exec span want child of begin span, but get parent %+v", exec.parent)
+		}
+
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if !begin.ended || begin.err != nil || begin.attributes["mydb.node"] != "master" {
+			t.Errorf("begin span want ended on commit %+v", begin)
+		}
+
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("success without tracer", func(t *testing.T) {
+		master, masterMock, err := sqlmock.New()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		masterMock.ExpectBegin()
+
+		db := New(master)
+		defer db.Close()
+
+		if _, err := db.Begin(); err != nil {
+			t.Error(err)
+		}
+		if err := masterMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
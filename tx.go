@@ -0,0 +1,122 @@
+package mydb
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// Tx is a transaction on master started by BeginTracedTx.
+// Its span covers the transaction until Commit or Rollback, and statements on it
+// are traced as its children with the node which serves them.
+type Tx struct {
+	*sql.Tx
+	db   *DB
+	ctx  context.Context // carries the span of the transaction
+	span Span
+	once sync.Once
+}
+
+// txContext is the context of a statement in a transaction. Values, including
+// the span, are taken from the transaction first, so that the statement span is
+// a child of the transaction span. Cancellation and deadline are of the statement.
+type txContext struct {
+	context.Context
+	tx context.Context
+}
+
+func (c txContext) Value(key interface{}) interface{} {
+	if v := c.tx.Value(key); v != nil {
+		return v
+	}
+	return c.Context.Value(key)
+}
+
+// Context returns the context carrying the span of the transaction.
+func (tx *Tx) Context() context.Context {
+	return tx.ctx
+}
+
+// BeginTracedTx starts a transaction like BeginTx, but the span of the transaction
+// ends on Commit or Rollback instead of when the transaction has started.
+func (db *DB) BeginTracedTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	ctx, span := db.startSpan(ctx, opBegin, "")
+	res, err := db.intercept(ctx, &Call{Method: "BeginTx", TxOptions: opts}, db.beginTx)
+	tx, ok := res.(*sql.Tx)
+	if err == nil && (!ok || tx == nil) {
+		err = ErrNilResult
+	}
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	return &Tx{Tx: tx, db: db, ctx: ctx, span: span}, nil
+}
+
+func (tx *Tx) Commit() error {
+	err := tx.Tx.Commit()
+	tx.end(err)
+	return err
+}
+
+func (tx *Tx) Rollback() error {
+	err := tx.Tx.Rollback()
+	tx.end(err)
+	return err
+}
+
+// end ends the span of the transaction once, so that a Rollback deferred after
+// Commit does not record sql.ErrTxDone.
+func (tx *Tx) end(err error) {
+	tx.once.Do(func() {
+		endSpan(tx.span, err)
+	})
+}
+
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.ExecContext(context.Background(), query, args...)
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := tx.db.startSpan(txContext{Context: ctx, tx: tx.ctx}, opExec, query)
+	traceNode(ctx, tx.db.masterNode)
+	res, err := tx.Tx.ExecContext(ctx, query, args...)
+	endSpan(span, err)
+	return res, err
+}
+
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.QueryContext(context.Background(), query, args...)
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := tx.db.startSpan(txContext{Context: ctx, tx: tx.ctx}, opQuery, query)
+	traceNode(ctx, tx.db.masterNode)
+	rows, err := tx.Tx.QueryContext(ctx, query, args...)
+	endSpan(span, err)
+	return rows, err
+}
+
+func (tx *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.QueryRowContext(context.Background(), query, args...)
+}
+
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := tx.db.startSpan(txContext{Context: ctx, tx: tx.ctx}, opQuery, query)
+	traceNode(ctx, tx.db.masterNode)
+	row := tx.Tx.QueryRowContext(ctx, query, args...)
+	endSpan(span, row.Err())
+	return row
+}
+
+func (tx *Tx) Prepare(query string) (*sql.Stmt, error) {
+	return tx.PrepareContext(context.Background(), query)
+}
+
+func (tx *Tx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := tx.db.startSpan(txContext{Context: ctx, tx: tx.ctx}, opPrepare, query)
+	traceNode(ctx, tx.db.masterNode)
+	stmt, err := tx.Tx.PrepareContext(ctx, query)
+	endSpan(span, err)
+	return stmt, err
+}